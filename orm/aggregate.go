@@ -11,6 +11,82 @@ func (a Aggregate) selectable() {
 
 }
 
+func (a Aggregate) expr() {}
+
+// Eq 用在 HAVING 里面
+// 例如 Avg("Age").Eq(18)
+func (a Aggregate) Eq(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opEq,
+		right: valueOf(arg),
+	}
+}
+
+func (a Aggregate) LT(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opLT,
+		right: valueOf(arg),
+	}
+}
+
+func (a Aggregate) GT(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opGT,
+		right: valueOf(arg),
+	}
+}
+
+func (a Aggregate) LTEQ(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opLTEQ,
+		right: valueOf(arg),
+	}
+}
+
+func (a Aggregate) GTEQ(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opGTEQ,
+		right: valueOf(arg),
+	}
+}
+
+func (a Aggregate) NEQ(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opNEQ,
+		right: valueOf(arg),
+	}
+}
+
+func (a Aggregate) In(vals ...any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opIn,
+		right: valueList{vals: vals},
+	}
+}
+
+func (a Aggregate) NotIn(vals ...any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opNotIn,
+		right: valueList{vals: vals},
+	}
+}
+
+func (a Aggregate) Between(start, end any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opBetween,
+		right: betweenExpr{start: valueOf(start), end: valueOf(end)},
+	}
+}
+
 func (a Aggregate) As(alias string) Aggregate {
 	return Aggregate{
 		fn:    a.fn,
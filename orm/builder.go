@@ -1,18 +1,61 @@
 package orm
 
 import (
+	"database/sql"
+	"reflect"
 	"scaffolding-go/orm/internal/errs"
+	"scaffolding-go/orm/model"
 	"strings"
+	"time"
 )
 
 type builder struct {
 	core
 	sb   strings.Builder
 	args []any
+	// argOffset 是前面已经有的参数个数
+	// 作为子查询的时候，PostgreSQL 的占位符要接着外层查询编号
+	argOffset int
+	// autoValues 是 Build 的时候自动生成的值，执行成功之后写回实体
+	autoValues []autoValue
 
 	quoter byte
 }
 
+type autoValue struct {
+	entity any
+	field  string
+	val    any
+}
+
+type argOffsetSetter interface {
+	setArgOffset(offset int)
+}
+
+// reset 清空上一次 Build 的结果
+// 中间件和子查询都可能多次调用 Build
+func (b *builder) reset() {
+	b.sb.Reset()
+	b.args = nil
+	b.autoValues = nil
+}
+
+// addAutoValue 记录自动生成的值，参考 writeAutoValues
+func (b *builder) addAutoValue(entity any, field string, val any) {
+	b.autoValues = append(b.autoValues, autoValue{entity: entity, field: field, val: val})
+}
+
+// writeAutoValues 把最近一次 Build 自动生成的值写回实体，例如创建时间和更新时间
+// 和自增主键一样，执行成功之后实体就是数据库里面的样子
+func (b *builder) writeAutoValues() error {
+	for _, av := range b.autoValues {
+		if err := b.creator(b.model, av.entity).SetField(av.field, av.val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (b *builder) quote(name string) {
 	b.sb.WriteByte(b.quoter)
 	b.sb.WriteString(name)
@@ -51,11 +94,286 @@ func (b *builder) buildColumn(col Column) error {
 			b.quote(col.alias)
 		}
 		return nil
+	case Subquery:
+		colName, err := b.subqueryColName(table, col.name)
+		if err != nil {
+			return err
+		}
+		if table.alias != "" {
+			b.quote(table.alias)
+			b.sb.WriteByte('.')
+		}
+		b.quote(colName)
+		if col.alias != "" {
+			b.sb.WriteString(" AS ")
+			b.quote(col.alias)
+		}
+		return nil
 	default:
 		return errs.NewErrUnsupportedTable(table)
 	}
 }
 
+// subqueryColName 找到子查询里面字段对应的列名
+// 子查询指定了列的时候，只能使用这些列，有别名的话用别名
+// 否则可以使用子查询 FROM 部分的所有列
+func (b *builder) subqueryColName(sub Subquery, name string) (string, error) {
+	if len(sub.columns) == 0 {
+		return b.tableColName(sub.entity, name)
+	}
+	for _, c := range sub.columns {
+		switch sc := c.(type) {
+		case Column:
+			if sc.alias != "" {
+				if sc.alias == name {
+					return sc.alias, nil
+				}
+				continue
+			}
+			if sc.name != name {
+				continue
+			}
+			tbl := sc.table
+			if tbl == nil {
+				tbl = sub.entity
+			}
+			return b.tableColName(tbl, name)
+		case Aggregate:
+			if sc.alias != "" && sc.alias == name {
+				return sc.alias, nil
+			}
+		case Subquery:
+			if sc.alias != "" && sc.alias == name {
+				return sc.alias, nil
+			}
+		}
+	}
+	return "", errs.NewErrUnknownField(name)
+}
+
+func (b *builder) tableColName(table TableReference, name string) (string, error) {
+	switch t := table.(type) {
+	case Table:
+		m, err := b.r.Get(t.entity)
+		if err != nil {
+			return "", err
+		}
+		fd, ok := m.FieldMap[name]
+		if !ok {
+			return "", errs.NewErrUnknownField(name)
+		}
+		return fd.ColName, nil
+	case Subquery:
+		return b.subqueryColName(t, name)
+	case Join:
+		// 先找左边，再找右边
+		colName, err := b.tableColName(t.left, name)
+		if err == nil {
+			return colName, nil
+		}
+		return b.tableColName(t.right, name)
+	default:
+		return "", errs.NewErrUnsupportedTable(table)
+	}
+}
+
+// buildSubquery 把子查询构造为 (SELECT ...)
+// 子查询的参数按照出现的位置合并进来
+func (b *builder) buildSubquery(sub Subquery, useAlias bool) error {
+	q, err := buildWithArgOffset(sub.s, b.argOffset+len(b.args))
+	if err != nil {
+		return err
+	}
+	b.sb.WriteByte('(')
+	// 去掉子查询末尾的分号
+	b.sb.WriteString(strings.TrimSuffix(q.SQL, ";"))
+	b.sb.WriteByte(')')
+	b.addArg(q.Args...)
+	if useAlias && sub.alias != "" {
+		b.sb.WriteString(" AS ")
+		b.quote(sub.alias)
+	}
+	return nil
+}
+
+func (b *builder) buildExpression(expr Expression) error {
+	switch exp := expr.(type) {
+	case nil:
+	case Predicate:
+		// 在这里处理 p
+		// p.left 构建好
+		// p.op 构建好
+		// p.right 构建好
+		if list, ok := exp.right.(valueList); ok && len(list.vals) == 0 {
+			return b.buildEmptyIn(exp.op)
+		}
+		return b.buildBinaryExpr(exp.left, exp.op, exp.right)
+	case MathExpr:
+		return b.buildBinaryExpr(exp.left, exp.op, exp.right)
+	case Aggregate:
+		// 用在 HAVING 里面，不需要别名
+		return b.buildAggregate(exp)
+	case Subquery:
+		return b.buildSubquery(exp, false)
+	case FuncExpr:
+		return b.buildFunc(exp)
+	case Column:
+		// 这种写法很隐晦
+		exp.alias = ""
+		return b.buildColumn(exp)
+	case value:
+		b.writeArg(exp.val)
+	case valueList:
+		b.sb.WriteByte('(')
+		for i, val := range exp.vals {
+			if i > 0 {
+				b.sb.WriteByte(',')
+			}
+			b.writeArg(val)
+		}
+		b.sb.WriteByte(')')
+	case betweenExpr:
+		if err := b.buildExpression(exp.start); err != nil {
+			return err
+		}
+		b.sb.WriteString(" AND ")
+		return b.buildExpression(exp.end)
+	case RawExpr:
+		b.sb.WriteByte('(')
+		b.buildRaw(exp)
+		b.sb.WriteByte(')')
+	default:
+		return errs.NewErrUnsupportedExpressionType(exp)
+	}
+	return nil
+}
+
+// buildBinaryExpr 构造 left op right 形态的表达式
+// 如果左右两边本身也是复合表达式，就用括号括起来
+func (b *builder) buildBinaryExpr(left Expression, op op, right Expression) error {
+	if err := b.buildSubExpr(left); err != nil {
+		return err
+	}
+	if op != "" {
+		b.sb.WriteByte(' ')
+		b.sb.WriteString(op.String())
+	}
+	// IS NULL 这种是没有右边的
+	if right == nil {
+		return nil
+	}
+	if op != "" {
+		b.sb.WriteByte(' ')
+	}
+	return b.buildSubExpr(right)
+}
+
+// buildEmptyIn 处理 IN 没有参数的情况
+// IN () 在 MySQL 里面是非法的，所以直接替换为恒假或者恒真
+func (b *builder) buildEmptyIn(op op) error {
+	switch op {
+	case opIn:
+		b.sb.WriteString("FALSE")
+	case opNotIn:
+		b.sb.WriteString("TRUE")
+	default:
+		return errs.NewErrUnsupportedExpressionType(op)
+	}
+	return nil
+}
+
+func (b *builder) buildSubExpr(expr Expression) error {
+	switch expr.(type) {
+	case Predicate, MathExpr:
+		b.sb.WriteByte('(')
+		if err := b.buildExpression(expr); err != nil {
+			return err
+		}
+		b.sb.WriteByte(')')
+		return nil
+	default:
+		return b.buildExpression(expr)
+	}
+}
+
+func (b *builder) buildAggregate(a Aggregate) error {
+	// 聚合函数名
+	b.sb.WriteString(a.fn)
+	b.sb.WriteByte('(')
+	if err := b.buildColumn(Column{name: a.arg}); err != nil {
+		return err
+	}
+	b.sb.WriteByte(')')
+	return nil
+}
+
+func (b *builder) buildFunc(f FuncExpr) error {
+	// 不是所有的数据库都有 CONCAT 函数
+	if f.fn == fnConcat {
+		return b.dialect.buildConcat(b, f.args)
+	}
+	b.sb.WriteString(f.fn)
+	if f.noParens {
+		return nil
+	}
+	b.sb.WriteByte('(')
+	if err := b.buildExpressions(f.args, ","); err != nil {
+		return err
+	}
+	b.sb.WriteByte(')')
+	return nil
+}
+
+// buildExpressions 构造多个表达式，中间用 sep 分隔
+func (b *builder) buildExpressions(exprs []Expression, sep string) error {
+	for i, expr := range exprs {
+		if i > 0 {
+			b.sb.WriteString(sep)
+		}
+		if err := b.buildExpression(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeArg 写入一个占位符，并且记录对应的参数
+func (b *builder) writeArg(val any) {
+	b.sb.WriteString(b.dialect.placeholder(b.argOffset + len(b.args) + 1))
+	b.addArg(val)
+}
+
+// buildRaw 原生表达式里面统一使用 ?，这里按照方言替换为对应的占位符
+func (b *builder) buildRaw(raw RawExpr) {
+	idx := b.argOffset + len(b.args)
+	for _, ch := range raw.raw {
+		if ch == '?' {
+			idx++
+			b.sb.WriteString(b.dialect.placeholder(idx))
+			continue
+		}
+		b.sb.WriteRune(ch)
+	}
+	b.addArg(raw.args...)
+}
+
+// setArgOffset 作为子查询或者组合查询的一部分时，设置前面已有的参数个数
+func (b *builder) setArgOffset(offset int) {
+	b.argOffset = offset
+}
+
+// buildWithArgOffset 作为外层查询的一部分构造 q，参数从 offset + 1 开始编号
+// 构造完之后恢复，q 单独 Build 的时候仍然从 1 开始编号
+func buildWithArgOffset(q QueryBuilder, offset int) (*Query, error) {
+	ob, ok := q.(argOffsetSetter)
+	if !ok {
+		return q.Build()
+	}
+	ob.setArgOffset(offset)
+	defer ob.setArgOffset(0)
+	return q.Build()
+}
+
 func (b *builder) addArg(vals ...any) {
 	if len(vals) == 0 {
 		return
@@ -65,3 +383,33 @@ func (b *builder) addArg(vals ...any) {
 	}
 	b.args = append(b.args, vals...)
 }
+
+// primaryKeyWhere 根据实体的主键构造 WHERE 条件
+// 模型没有主键的时候返回错误，避免生成没有 WHERE 的语句操作整张表
+func (b *builder) primaryKeyWhere(entity any) ([]Predicate, error) {
+	if len(b.model.PrimaryKeys) == 0 {
+		return nil, errs.ErrNoPrimaryKey
+	}
+	val := b.creator(b.model, entity)
+	res := make([]Predicate, 0, len(b.model.PrimaryKeys))
+	for _, pk := range b.model.PrimaryKeys {
+		arg, err := val.Field(pk.GoName)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, C(pk.GoName).Eq(arg))
+	}
+	return res, nil
+}
+
+// timeValue 时间类型的字段写入当前时间，整数类型的字段写入毫秒时间戳
+func timeValue(fd *model.Field, now time.Time) any {
+	typ := fd.Typ
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(time.Time{}) || typ == reflect.TypeOf(sql.NullTime{}) {
+		return now
+	}
+	return now.UnixMilli()
+}
@@ -0,0 +1,109 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+)
+
+// LoadBalancer 从库的负载均衡策略
+type LoadBalancer interface {
+	// Pick 返回这一次使用的从库下标，n 是从库的数量
+	Pick(n int) int
+}
+
+// DBWithSlaves 配置从库，实现读写分离
+// Selector 的查询会按照 lb 发给从库，其余的语句、事务里面的语句
+// 以及 UseMaster 标记过的 ctx 都发给主库
+// lb 是 nil 的时候使用轮询
+func DBWithSlaves(lb LoadBalancer, slaves ...*sql.DB) DBOption {
+	if lb == nil {
+		lb = NewRoundRobinBalancer()
+	}
+	return func(db *DB) {
+		db.lb = lb
+		db.slaves = slaves
+	}
+}
+
+type masterKey struct{}
+
+// UseMaster 强制使用主库，用于写完马上读的场景
+func UseMaster(ctx context.Context) context.Context {
+	return context.WithValue(ctx, masterKey{}, true)
+}
+
+func isMaster(ctx context.Context) bool {
+	master, _ := ctx.Value(masterKey{}).(bool)
+	return master
+}
+
+// readContext 只读的查询，有从库的时候发给从库
+func (db *DB) readContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if _, ok := db.txFromContext(ctx); ok || len(db.slaves) == 0 || isMaster(ctx) {
+		return db.queryContext(ctx, query, args...)
+	}
+	return db.slaves[db.lb.Pick(len(db.slaves))].QueryContext(ctx, query, args...)
+}
+
+// RoundRobinBalancer 轮询
+type RoundRobinBalancer struct {
+	cnt atomic.Uint64
+}
+
+func NewRoundRobinBalancer() *RoundRobinBalancer {
+	return &RoundRobinBalancer{}
+}
+
+func (r *RoundRobinBalancer) Pick(n int) int {
+	return int((r.cnt.Add(1) - 1) % uint64(n))
+}
+
+// RandomBalancer 随机
+type RandomBalancer struct{}
+
+func NewRandomBalancer() RandomBalancer {
+	return RandomBalancer{}
+}
+
+func (RandomBalancer) Pick(n int) int {
+	return rand.IntN(n)
+}
+
+// WeightedBalancer 平滑加权轮询，和 nginx 的算法一样
+// 权重按照从库的顺序，没有指定的从库权重是 1
+type WeightedBalancer struct {
+	mu      sync.Mutex
+	weights []int
+	current []int
+}
+
+func NewWeightedBalancer(weights ...int) *WeightedBalancer {
+	return &WeightedBalancer{
+		weights: weights,
+	}
+}
+
+func (w *WeightedBalancer) Pick(n int) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.current) != n {
+		w.current = make([]int, n)
+	}
+	total, best := 0, 0
+	for i := 0; i < n; i++ {
+		weight := 1
+		if i < len(w.weights) {
+			weight = w.weights[i]
+		}
+		w.current[i] += weight
+		total += weight
+		if w.current[i] > w.current[best] {
+			best = i
+		}
+	}
+	w.current[best] -= total
+	return best
+}
@@ -0,0 +1,127 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBalancer(t *testing.T) {
+	testCases := []struct {
+		name string
+		lb   LoadBalancer
+		n    int
+		want []int
+	}{
+		{
+			name: "round robin",
+			lb:   NewRoundRobinBalancer(),
+			n:    3,
+			want: []int{0, 1, 2, 0, 1},
+		},
+		{
+			name: "weighted",
+			lb:   NewWeightedBalancer(5, 1, 1),
+			n:    3,
+			want: []int{0, 0, 1, 0, 2, 0, 0},
+		},
+		{
+			name: "weighted default",
+			lb:   NewWeightedBalancer(2),
+			n:    2,
+			want: []int{0, 1, 0},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := make([]int, 0, len(tc.want))
+			for range tc.want {
+				res = append(res, tc.lb.Pick(tc.n))
+			}
+			assert.Equal(t, tc.want, res)
+		})
+	}
+
+	lb := NewRandomBalancer()
+	for i := 0; i < 10; i++ {
+		idx := lb.Pick(3)
+		assert.True(t, idx >= 0 && idx < 3)
+	}
+}
+
+func TestDBWithSlaves(t *testing.T) {
+	masterDB, master, err := sqlmock.New()
+	require.NoError(t, err)
+	slaveDB1, slave1, err := sqlmock.New()
+	require.NoError(t, err)
+	slaveDB2, slave2, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(masterDB, DBWithSlaves(NewRoundRobinBalancer(), slaveDB1, slaveDB2))
+	require.NoError(t, err)
+	ctx := context.Background()
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id"}).AddRow(1)
+	}
+
+	// Selector 轮询从库
+	slave1.ExpectQuery("SELECT .*").WillReturnRows(rows())
+	slave2.ExpectQuery("SELECT .*").WillReturnRows(rows())
+	for i := 0; i < 2; i++ {
+		_, err = NewSelector[TestModel](db).Get(ctx)
+		require.NoError(t, err)
+	}
+
+	// 写操作和原生查询发给主库
+	master.ExpectExec("INSERT .*").WillReturnResult(sqlmock.NewResult(1, 1))
+	require.NoError(t, NewInserter[TestModel](db).Values(&TestModel{}).Exec(ctx).Err())
+	master.ExpectQuery("SELECT .*").WillReturnRows(rows())
+	_, err = RawQuery[TestModel](db, "SELECT * FROM `test_model`").Get(ctx)
+	require.NoError(t, err)
+
+	// 强制使用主库
+	master.ExpectQuery("SELECT .*").WillReturnRows(rows())
+	_, err = NewSelector[TestModel](db).Get(UseMaster(ctx))
+	require.NoError(t, err)
+
+	// 事务里面都发给主库
+	master.ExpectBegin()
+	master.ExpectQuery("SELECT .*").WillReturnRows(rows())
+	master.ExpectQuery("SELECT .*").WillReturnRows(rows())
+	master.ExpectCommit()
+	err = db.DoTx(ctx, func(ctx context.Context, tx *Tx) error {
+		if _, err := NewSelector[TestModel](db).Get(ctx); err != nil {
+			return err
+		}
+		_, err := NewSelector[TestModel](tx).Get(ctx)
+		return err
+	}, nil)
+	require.NoError(t, err)
+
+	assert.NoError(t, master.ExpectationsWereMet())
+	assert.NoError(t, slave1.ExpectationsWereMet())
+	assert.NoError(t, slave2.ExpectationsWereMet())
+}
+
+func TestDBWithSlaves_NilBalancer(t *testing.T) {
+	masterDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	slaveDB1, slave1, err := sqlmock.New()
+	require.NoError(t, err)
+	slaveDB2, slave2, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(masterDB, DBWithSlaves(nil, slaveDB1, slaveDB2))
+	require.NoError(t, err)
+
+	// 默认轮询
+	slave1.ExpectQuery("SELECT .*").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	slave2.ExpectQuery("SELECT .*").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	for i := 0; i < 2; i++ {
+		_, err = NewSelector[TestModel](db).Get(context.Background())
+		require.NoError(t, err)
+	}
+	assert.NoError(t, slave1.ExpectationsWereMet())
+	assert.NoError(t, slave2.ExpectationsWereMet())
+}
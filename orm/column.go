@@ -50,7 +50,136 @@ func (c Column) LT(arg any) Predicate {
 	return Predicate{
 		left:  c,
 		op:    opLT,
-		right: value{val: arg},
+		right: valueOf(arg),
+	}
+}
+
+// Add 代表 c + delta
+// 例如更新的时候 Assign("Age", C("Age").Add(1))
+func (c Column) Add(delta any) MathExpr {
+	return MathExpr{
+		left:  c,
+		op:    opAdd,
+		right: valueOf(delta),
+	}
+}
+
+func (c Column) Sub(delta any) MathExpr {
+	return MathExpr{
+		left:  c,
+		op:    opSub,
+		right: valueOf(delta),
+	}
+}
+
+func (c Column) Multi(delta any) MathExpr {
+	return MathExpr{
+		left:  c,
+		op:    opMulti,
+		right: valueOf(delta),
+	}
+}
+
+func (c Column) Div(delta any) MathExpr {
+	return MathExpr{
+		left:  c,
+		op:    opDiv,
+		right: valueOf(delta),
+	}
+}
+
+// GT 代表大于
+func (c Column) GT(arg any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opGT,
+		right: valueOf(arg),
+	}
+}
+
+// LTEQ 代表小于等于
+func (c Column) LTEQ(arg any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opLTEQ,
+		right: valueOf(arg),
+	}
+}
+
+// GTEQ 代表大于等于
+func (c Column) GTEQ(arg any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opGTEQ,
+		right: valueOf(arg),
+	}
+}
+
+// NEQ 代表不等于
+func (c Column) NEQ(arg any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opNEQ,
+		right: valueOf(arg),
+	}
+}
+
+// Like 例如 C("Name").Like("%Tom%")
+func (c Column) Like(pattern string) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opLike,
+		right: valueOf(pattern),
+	}
+}
+
+func (c Column) NotLike(pattern string) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opNotLike,
+		right: valueOf(pattern),
+	}
+}
+
+// In 例如 C("Id").In(1, 2, 3)
+// 如果没有传入任何值，那么这个条件永远不成立
+func (c Column) In(vals ...any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opIn,
+		right: valueList{vals: vals},
+	}
+}
+
+// NotIn 如果没有传入任何值，那么这个条件永远成立
+func (c Column) NotIn(vals ...any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opNotIn,
+		right: valueList{vals: vals},
+	}
+}
+
+// Between 代表 start <= c <= end
+func (c Column) Between(start, end any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opBetween,
+		right: betweenExpr{start: valueOf(start), end: valueOf(end)},
+	}
+}
+
+func (c Column) IsNull() Predicate {
+	return Predicate{
+		left: c,
+		op:   opIsNull,
+	}
+}
+
+func (c Column) IsNotNull() Predicate {
+	return Predicate{
+		left: c,
+		op:   opIsNotNull,
 	}
 }
 
@@ -0,0 +1,292 @@
+package orm
+
+import (
+	"reflect"
+	"scaffolding-go/orm/internal/errs"
+	"strings"
+)
+
+// compoundQuery 代表可以参与 UNION 之类组合查询的查询
+type compoundQuery interface {
+	QueryBuilder
+	compoundCore() core
+	compoundColumns() ([]compoundColumn, error)
+	// compoundOrdered 查询自己有没有 ORDER BY 或者分页
+	compoundOrdered() bool
+}
+
+// compoundColumn 代表查询结果集中的一列
+type compoundColumn struct {
+	// 字段名或者别名，ORDER BY 用它来找列
+	name string
+	// 结果集里面的列名
+	colName string
+	// 列对应的 Go 类型，聚合函数之类的无法确定，为 nil
+	typ reflect.Type
+}
+
+// Compound 代表组合查询
+// 例如 Union(s1, s2).All().OrderBy(Desc("Id")).Limit(10)
+// 可以通过 RawQueryOf 把结果扫描到 T 里面
+type Compound struct {
+	builder
+	typ     string
+	all     bool
+	queries []compoundQuery
+	orderBy []OrderBy
+	limit   int
+	offset  int
+}
+
+// readOnly 组合查询的成员都是 SELECT，RawQueryOf 执行的时候类型也是 SELECT
+func (c *Compound) readOnly() {}
+
+func Union(qs ...compoundQuery) *Compound {
+	return &Compound{
+		typ:     "UNION",
+		queries: qs,
+	}
+}
+
+func Intersect(qs ...compoundQuery) *Compound {
+	return &Compound{
+		typ:     "INTERSECT",
+		queries: qs,
+	}
+}
+
+// All 保留重复的行，例如 UNION ALL
+func (c *Compound) All() *Compound {
+	c.all = true
+	return c
+}
+
+// OrderBy 对整个组合查询的结果排序
+// 列以第一个查询的列为准
+func (c *Compound) OrderBy(obs ...OrderBy) *Compound {
+	c.orderBy = obs
+	return c
+}
+
+func (c *Compound) Limit(limit int) *Compound {
+	c.limit = limit
+	return c
+}
+
+func (c *Compound) Offset(offset int) *Compound {
+	c.offset = offset
+	return c
+}
+
+func (c *Compound) Build() (*Query, error) {
+	if len(c.queries) < 2 {
+		return nil, errs.ErrCompoundTooFewQueries
+	}
+	c.reset()
+	c.core = c.queries[0].compoundCore()
+	c.quoter = c.dialect.quoter()
+
+	var first []compoundColumn
+	for i, q := range c.queries {
+		cols, err := q.compoundColumns()
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			first = cols
+		} else if err = c.checkColumns(i, first, cols); err != nil {
+			return nil, err
+		}
+
+		// 成员自己的 ORDER BY 和 LIMIT 要用括号括起来，否则会被当成整个组合查询的
+		ordered := q.compoundOrdered()
+		if ordered && !c.dialect.compoundParens() {
+			return nil, errs.NewErrCompoundOrderedMember(i)
+		}
+		sub, err := buildWithArgOffset(q, c.argOffset+len(c.args))
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			c.sb.WriteByte(' ')
+			c.sb.WriteString(c.typ)
+			if c.all {
+				c.sb.WriteString(" ALL")
+			}
+			c.sb.WriteByte(' ')
+		}
+		if ordered {
+			c.sb.WriteByte('(')
+		}
+		c.sb.WriteString(strings.TrimSuffix(sub.SQL, ";"))
+		if ordered {
+			c.sb.WriteByte(')')
+		}
+		c.addArg(sub.Args...)
+	}
+
+	if len(c.orderBy) > 0 {
+		c.sb.WriteString(" ORDER BY ")
+		for i, ob := range c.orderBy {
+			if i > 0 {
+				c.sb.WriteByte(',')
+			}
+			col, ok := findCompoundColumn(first, ob.col)
+			if !ok {
+				return nil, errs.NewErrUnknownField(ob.col)
+			}
+			c.quote(col.colName)
+			c.sb.WriteByte(' ')
+			c.sb.WriteString(ob.order)
+		}
+	}
+	if c.limit > 0 || c.offset > 0 {
+		if err := c.dialect.buildLimit(&c.builder, c.limit, c.offset); err != nil {
+			return nil, err
+		}
+	}
+	c.sb.WriteByte(';')
+	return &Query{
+		SQL:  c.sb.String(),
+		Args: c.args,
+	}, nil
+}
+
+// checkColumns 检查列数和列的类型
+// 无法确定列的时候（例如 SELECT * FROM 子查询）跳过检查
+func (c *Compound) checkColumns(idx int, first, cols []compoundColumn) error {
+	if first == nil || cols == nil {
+		return nil
+	}
+	if len(first) != len(cols) {
+		return errs.NewErrCompoundColumnCount(idx, len(cols), len(first))
+	}
+	for i, col := range cols {
+		if first[i].typ == nil || col.typ == nil {
+			continue
+		}
+		if first[i].typ != col.typ {
+			return errs.NewErrCompoundColumnType(idx, col.colName, col.typ, first[i].typ)
+		}
+	}
+	return nil
+}
+
+func findCompoundColumn(cols []compoundColumn, name string) (compoundColumn, bool) {
+	for _, col := range cols {
+		if col.name == name {
+			return col, true
+		}
+	}
+	return compoundColumn{}, false
+}
+
+func (s *Selector[T]) compoundCore() core {
+	return s.core
+}
+
+func (s *Selector[T]) compoundOrdered() bool {
+	return len(s.orderBy) > 0 || s.limit > 0 || s.offset > 0
+}
+
+// compoundColumns 根据元数据计算查询结果集的列
+func (s *Selector[T]) compoundColumns() ([]compoundColumn, error) {
+	if s.model == nil {
+		var err error
+		s.model, err = s.r.Get(new(T))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(s.columns) == 0 {
+		m := s.model
+		switch t := s.table.(type) {
+		case nil:
+		case Table:
+			var err error
+			m, err = s.r.Get(t.entity)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			// JOIN 或者子查询，SELECT * 的列无法确定
+			return nil, nil
+		}
+		res := make([]compoundColumn, 0, len(m.Fields))
+		for _, fd := range m.Fields {
+			res = append(res, compoundColumn{
+				name:    fd.GoName,
+				colName: fd.ColName,
+				typ:     fd.Typ,
+			})
+		}
+		return res, nil
+	}
+	res := make([]compoundColumn, 0, len(s.columns))
+	for _, sc := range s.columns {
+		switch c := sc.(type) {
+		case Column:
+			col, err := s.compoundColumnOf(c)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, col)
+		case Aggregate:
+			res = append(res, compoundColumn{
+				name:    c.alias,
+				colName: c.alias,
+			})
+		case Subquery:
+			res = append(res, compoundColumn{
+				name:    c.alias,
+				colName: c.alias,
+			})
+		case MathExpr:
+			res = append(res, compoundColumn{
+				name:    c.alias,
+				colName: c.alias,
+			})
+		case FuncExpr:
+			res = append(res, compoundColumn{
+				name:    c.alias,
+				colName: c.alias,
+			})
+		default:
+			res = append(res, compoundColumn{})
+		}
+	}
+	return res, nil
+}
+
+func (s *Selector[T]) compoundColumnOf(c Column) (compoundColumn, error) {
+	m := s.model
+	switch tbl := c.table.(type) {
+	case nil:
+	case Table:
+		var err error
+		m, err = s.r.Get(tbl.entity)
+		if err != nil {
+			return compoundColumn{}, err
+		}
+	case Subquery:
+		// 子查询的列，类型无法确定
+		colName, err := s.subqueryColName(tbl, c.name)
+		if err != nil {
+			return compoundColumn{}, err
+		}
+		if c.alias != "" {
+			return compoundColumn{name: c.alias, colName: c.alias}, nil
+		}
+		return compoundColumn{name: c.name, colName: colName}, nil
+	default:
+		return compoundColumn{}, errs.NewErrUnsupportedTable(tbl)
+	}
+	fd, ok := m.FieldMap[c.name]
+	if !ok {
+		return compoundColumn{}, errs.NewErrUnknownField(c.name)
+	}
+	if c.alias != "" {
+		return compoundColumn{name: c.alias, colName: c.alias, typ: fd.Typ}, nil
+	}
+	return compoundColumn{name: c.name, colName: fd.ColName, typ: fd.Typ}, nil
+}
@@ -0,0 +1,190 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"scaffolding-go/orm/internal/errs"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompound_Build(t *testing.T) {
+	db := memoryDB(t)
+	sqliteDB := memoryDB(t, DBWithDialect(DialectSQLite))
+	type Order202401 struct {
+		Id     int64
+		UserId int64
+		Amount int64
+	}
+	type Order202402 struct {
+		Id     int64
+		UserId int64
+		Amount int64
+	}
+	type BadOrder struct {
+		Id     string
+		UserId int64
+		Amount int64
+	}
+
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "union",
+			q: Union(
+				NewSelector[Order202401](db).Where(C("UserId").Eq(12)),
+				NewSelector[Order202402](db).Where(C("UserId").Eq(13)),
+			),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `order202401` WHERE `user_id` = ? UNION " +
+					"SELECT * FROM `order202402` WHERE `user_id` = ?;",
+				Args: []any{12, 13},
+			},
+		},
+		{
+			name: "union all order by limit",
+			q: Union(
+				NewSelector[Order202401](db).Select(C("Id"), C("Amount")).Where(C("Amount").GT(10)),
+				NewSelector[Order202402](db).Select(C("Id"), C("Amount")).Where(C("Amount").GT(20)),
+			).All().OrderBy(Desc("Amount")).Limit(10).Offset(5),
+			wantQuery: &Query{
+				SQL: "SELECT `id`,`amount` FROM `order202401` WHERE `amount` > ? UNION ALL " +
+					"SELECT `id`,`amount` FROM `order202402` WHERE `amount` > ? " +
+					"ORDER BY `amount` DESC LIMIT ? OFFSET ?;",
+				Args: []any{10, 20, 10, 5},
+			},
+		},
+		{
+			name: "intersect",
+			q: Intersect(
+				NewSelector[Order202401](db).Select(C("UserId")),
+				NewSelector[Order202402](db).Select(C("UserId")),
+				NewSelector[Order202402](db).FROM(TableOf(&Order202401{})).Select(C("UserId")),
+			),
+			wantQuery: &Query{
+				SQL: "SELECT `user_id` FROM `order202401` INTERSECT SELECT `user_id` FROM `order202402` " +
+					"INTERSECT SELECT `user_id` FROM `order202401`;",
+			},
+		},
+		{
+			name: "aggregate alias",
+			q: Union(
+				NewSelector[Order202401](db).Select(C("UserId"), Sum("Amount").As("total")).GroupBy(C("UserId")),
+				NewSelector[Order202402](db).Select(C("UserId"), Sum("Amount").As("total")).GroupBy(C("UserId")),
+			).All().OrderBy(Desc("total")),
+			wantQuery: &Query{
+				SQL: "SELECT `user_id`,SUM(`amount`) AS `total` FROM `order202401` GROUP BY `user_id` UNION ALL " +
+					"SELECT `user_id`,SUM(`amount`) AS `total` FROM `order202402` GROUP BY `user_id` " +
+					"ORDER BY `total` DESC;",
+			},
+		},
+		{
+			name: "member order by limit",
+			q: Union(
+				NewSelector[Order202401](db).Where(C("UserId").Eq(12)).OrderBy(Desc("Amount")).Limit(3),
+				NewSelector[Order202402](db).Where(C("UserId").Eq(13)),
+			).All().Limit(5),
+			wantQuery: &Query{
+				SQL: "(SELECT * FROM `order202401` WHERE `user_id` = ? ORDER BY `amount` DESC LIMIT ?) UNION ALL " +
+					"SELECT * FROM `order202402` WHERE `user_id` = ? LIMIT ?;",
+				Args: []any{12, 3, 13, 5},
+			},
+		},
+		{
+			name: "sqlite member order by",
+			q: Union(
+				NewSelector[Order202401](sqliteDB).Select(C("Id")),
+				NewSelector[Order202402](sqliteDB).Select(C("Id")).Limit(3),
+			),
+			wantErr: errs.NewErrCompoundOrderedMember(1),
+		},
+		{
+			name:    "too few queries",
+			q:       Union(NewSelector[Order202401](db)),
+			wantErr: errs.ErrCompoundTooFewQueries,
+		},
+		{
+			name: "column count mismatch",
+			q: Union(
+				NewSelector[Order202401](db).Select(C("Id"), C("Amount")),
+				NewSelector[Order202402](db).Select(C("Id")),
+			),
+			wantErr: errs.NewErrCompoundColumnCount(1, 1, 2),
+		},
+		{
+			name: "column type mismatch",
+			q: Union(
+				NewSelector[Order202401](db),
+				NewSelector[BadOrder](db),
+			),
+			wantErr: errs.NewErrCompoundColumnType(1, "id",
+				reflect.TypeOf(""), reflect.TypeOf(int64(0))),
+		},
+		{
+			name: "invalid order by",
+			q: Union(
+				NewSelector[Order202401](db).Select(C("Id")),
+				NewSelector[Order202402](db).Select(C("Id")),
+			).OrderBy(Asc("Amount")),
+			wantErr: errs.NewErrUnknownField("Amount"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestCompound_GetMulti(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	type TestModel2 struct {
+		Id        int64
+		FirstName string
+		Age       int8
+		LastName  *sql.NullString
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow("1", "Tom", "18", "Jerry")
+	rows.AddRow("2", "Bob", "20", "Allice")
+	mock.ExpectQuery("SELECT .* UNION ALL SELECT .*").WithArgs(18, 18).WillReturnRows(rows)
+
+	u := Union(
+		NewSelector[TestModel](db).Where(C("Age").GTEQ(18)),
+		NewSelector[TestModel2](db).Where(C("Age").GTEQ(18)),
+	).All()
+	res, err := RawQueryOf[TestModel](db, u).GetMulti(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*TestModel{
+		{
+			Id:        1,
+			FirstName: "Tom",
+			Age:       18,
+			LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+		},
+		{
+			Id:        2,
+			FirstName: "Bob",
+			Age:       20,
+			LastName:  &sql.NullString{Valid: true, String: "Allice"},
+		},
+	}, res)
+}
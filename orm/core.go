@@ -2,8 +2,14 @@ package orm
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"iter"
+	"reflect"
+	"scaffolding-go/orm/internal/errs"
 	"scaffolding-go/orm/internal/valuer"
 	"scaffolding-go/orm/model"
+	"time"
 )
 
 type core struct {
@@ -12,9 +18,38 @@ type core struct {
 	creator valuer.Creator
 	r       model.Registry
 	mdls    []Middleware
+	// now 获取当前时间，软删除之类的功能会用到
+	now func() time.Time
+}
+
+// readOnly 标记只读的查询，配置了从库的时候会发给从库
+type readOnly interface {
+	readOnly()
+}
+
+// sessionTx 语句实际所在的事务，DB 会使用 ctx 里面的事务
+func sessionTx(ctx context.Context, sess Session) *Tx {
+	switch s := sess.(type) {
+	case *Tx:
+		return s
+	case *DB:
+		if tx, ok := s.txFromContext(ctx); ok {
+			return tx
+		}
+	}
+	return nil
+}
+
+func query(ctx context.Context, sess Session, qc *QueryContext, q *Query) (*sql.Rows, error) {
+	if _, ok := qc.Builder.(readOnly); ok {
+		return sess.readContext(ctx, q.SQL, q.Args...)
+	}
+	return sess.queryContext(ctx, q.SQL, q.Args...)
 }
 
 func get[T any](ctx context.Context, sess Session, c core, qc *QueryContext) *QueryResult {
+	qc.ResultType = reflect.TypeOf(new(T))
+	qc.Tx = sessionTx(ctx, sess)
 	var root Handler = func(ctx context.Context, qc *QueryContext) *QueryResult {
 		return getHandler[T](ctx, sess, c, qc)
 	}
@@ -33,15 +68,23 @@ func getHandler[T any](ctx context.Context, sess Session, c core, qc *QueryConte
 		}
 	}
 	// 在这里发起查询，并且处理结果集
-	rows, err := sess.queryContext(ctx, q.SQL, q.Args...)
+	rows, err := query(ctx, sess, qc, q)
 	// 这个是查询的错误
 	if err != nil {
 		return &QueryResult{
 			Err: err,
 		}
 	}
+	defer func() {
+		_ = rows.Close()
+	}()
 	// 你要确认有没有数据
 	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
 		// 要不要返回error?
 		// 返回error 和 sql包语义保持一致
 		return &QueryResult{
@@ -51,6 +94,9 @@ func getHandler[T any](ctx context.Context, sess Session, c core, qc *QueryConte
 	tp := new(T)
 	val := c.creator(c.model, tp)
 	err = val.SetColumns(rows)
+	if err == nil {
+		err = afterFind(ctx, tp)
+	}
 	// 接口定义好后就两件事，一个是用新接口的方法改造上层，
 	// 一个就是提供不同的实现
 	return &QueryResult{
@@ -59,7 +105,134 @@ func getHandler[T any](ctx context.Context, sess Session, c core, qc *QueryConte
 	}
 }
 
+func getMulti[T any](ctx context.Context, sess Session, c core, qc *QueryContext) *QueryResult {
+	qc.ResultType = reflect.TypeOf([]*T(nil))
+	qc.Tx = sessionTx(ctx, sess)
+	var root Handler = func(ctx context.Context, qc *QueryContext) *QueryResult {
+		return getMultiHandler[T](ctx, sess, c, qc)
+	}
+	for i := len(c.mdls) - 1; i >= 0; i-- {
+		root = c.mdls[i](root)
+	}
+	return root(ctx, qc)
+}
+
+func getMultiHandler[T any](ctx context.Context, sess Session, c core, qc *QueryContext) *QueryResult {
+	q, err := qc.Builder.Build()
+	if err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
+	rows, err := query(ctx, sess, qc, q)
+	if err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	// 和 Get 不同，没有数据的时候返回空切片，而不是 ErrNoRows
+	res := make([]*T, 0, 8)
+	for rows.Next() {
+		tp := new(T)
+		val := c.creator(c.model, tp)
+		if err = val.SetColumns(rows); err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+		if err = afterFind(ctx, tp); err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+		res = append(res, tp)
+	}
+	// 遍历过程中可能出错，例如网络中断
+	if err = rows.Err(); err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
+	return &QueryResult{
+		Result: res,
+	}
+}
+
+// iterate 返回一个迭代器，每次迭代只扫描一行
+// 中间件在真正遍历之前执行，此时 QueryResult.Result 是 *sql.Rows
+func iterate[T any](ctx context.Context, sess Session, c core, qc *QueryContext) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		qc.Tx = sessionTx(ctx, sess)
+		var root Handler = func(ctx context.Context, qc *QueryContext) *QueryResult {
+			return iterHandler(ctx, sess, qc)
+		}
+		for i := len(c.mdls) - 1; i >= 0; i-- {
+			root = c.mdls[i](root)
+		}
+		res := root(ctx, qc)
+		if res.Err != nil {
+			yield(nil, res.Err)
+			return
+		}
+		rows, ok := res.Result.(*sql.Rows)
+		if !ok {
+			yield(nil, errs.NewErrUnsupportedQueryResult(res.Result))
+			return
+		}
+		defer func() {
+			_ = rows.Close()
+		}()
+		for rows.Next() {
+			// 用户可能在遍历过程中取消
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			tp := new(T)
+			val := c.creator(c.model, tp)
+			if err := val.SetColumns(rows); err != nil {
+				yield(nil, err)
+				return
+			}
+			if err := afterFind(ctx, tp); err != nil {
+				yield(nil, err)
+				return
+			}
+			// 用户 break 了
+			if !yield(tp, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+func iterHandler(ctx context.Context, sess Session, qc *QueryContext) *QueryResult {
+	q, err := qc.Builder.Build()
+	if err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
+	// rows 交给迭代器关闭
+	rows, err := query(ctx, sess, qc, q)
+	if err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
+	return &QueryResult{
+		Result: rows,
+	}
+}
+
 func exec(ctx context.Context, sess Session, c core, qc *QueryContext) *QueryResult {
+	qc.Tx = sessionTx(ctx, sess)
 	var root Handler = func(ctx context.Context, qc *QueryContext) *QueryResult {
 		return execHandler(ctx, sess, c, qc)
 	}
@@ -69,6 +242,69 @@ func exec(ctx context.Context, sess Session, c core, qc *QueryContext) *QueryRes
 	return root(ctx, qc)
 }
 
+// execReturning 用于 INSERT ... RETURNING
+// 返回的每一行按照顺序写回 vals
+func execReturning[T any](ctx context.Context, sess Session, c core, qc *QueryContext, vals []*T) *QueryResult {
+	qc.Tx = sessionTx(ctx, sess)
+	var root Handler = func(ctx context.Context, qc *QueryContext) *QueryResult {
+		return execReturningHandler[T](ctx, sess, c, qc, vals)
+	}
+	for i := len(c.mdls) - 1; i >= 0; i-- {
+		root = c.mdls[i](root)
+	}
+	return root(ctx, qc)
+}
+
+func execReturningHandler[T any](ctx context.Context, sess Session, c core, qc *QueryContext, vals []*T) *QueryResult {
+	q, err := qc.Builder.Build()
+	if err != nil {
+		return &QueryResult{
+			Result: Result{
+				err: err,
+			},
+			Err: err,
+		}
+	}
+	rows, err := query(ctx, sess, qc, q)
+	if err != nil {
+		return &QueryResult{
+			Result: Result{
+				err: err,
+			},
+			Err: err,
+		}
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var affected int64
+	// PostgreSQL 多行插入的时候，RETURNING 的顺序和 VALUES 的顺序一致
+	for rows.Next() && int(affected) < len(vals) {
+		if err = c.creator(c.model, vals[affected]).SetColumns(rows); err != nil {
+			return &QueryResult{
+				Result: Result{
+					err: err,
+				},
+				Err: err,
+			}
+		}
+		affected++
+	}
+	if err = rows.Err(); err != nil {
+		return &QueryResult{
+			Result: Result{
+				err: err,
+			},
+			Err: err,
+		}
+	}
+	return &QueryResult{
+		Result: Result{
+			res: driver.RowsAffected(affected),
+		},
+	}
+}
+
 func execHandler(ctx context.Context, sess Session, c core, qc *QueryContext) *QueryResult {
 	q, err := qc.Builder.Build()
 	if err != nil {
@@ -76,6 +312,7 @@ func execHandler(ctx context.Context, sess Session, c core, qc *QueryContext) *Q
 			Result: Result{
 				err: err,
 			},
+			Err: err,
 		}
 	}
 	res, err := sess.execContext(ctx, q.SQL, q.Args...)
@@ -84,5 +321,6 @@ func execHandler(ctx context.Context, sess Session, c core, qc *QueryContext) *Q
 			err: err,
 			res: res,
 		},
+		Err: err,
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"log"
 	"scaffolding-go/orm/internal/valuer"
 	"scaffolding-go/orm/model"
+	"time"
 )
 
 type DBOption func(db *DB)
@@ -16,6 +17,11 @@ type DBOption func(db *DB)
 type DB struct {
 	core
 	db *sql.DB
+	// 从库，参考 DBWithSlaves
+	slaves []*sql.DB
+	lb     LoadBalancer
+	// 分片所在的数据库，参考 DBWithShards
+	shards map[string]*sql.DB
 }
 
 func Open(driver string, dataSourceName string, opts ...DBOption) (*DB, error) {
@@ -35,6 +41,7 @@ func OpenDB(db *sql.DB, opts ...DBOption) (*DB, error) {
 			r:       model.NewRegistry(),
 			creator: valuer.NewUnsafeValue,
 			dialect: DialectMySQL,
+			now:     time.Now,
 		},
 		db: db,
 	}
@@ -84,6 +91,7 @@ func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
 	}
 	return &Tx{
 		tx: tx,
+		db: db,
 	}, nil
 }
 
@@ -92,10 +100,8 @@ type txKey struct{}
 // 事务的扩散方案
 func (db *DB) BeginTxV2(ctx context.Context,
 	opts *sql.TxOptions) (context.Context, *Tx, error) {
-	val := ctx.Value(txKey{})
-	tx, ok := val.(*Tx)
 	// 存在一个事务并且没有被提交或者回滚
-	if ok && !tx.done {
+	if tx, ok := db.txFromContext(ctx); ok {
 		return ctx, tx, nil
 	}
 	tx, err := db.BeginTx(ctx, opts)
@@ -106,11 +112,27 @@ func (db *DB) BeginTxV2(ctx context.Context,
 	return ctx, tx, nil
 }
 
+// txFromContext 拿到 ctx 里面这个 DB 开启的，还没有结束的事务
+func (db *DB) txFromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	if !ok || tx.db != db || tx.done {
+		return nil, false
+	}
+	return tx, true
+}
+
+// queryContext ctx 里面有事务的时候，在事务里面执行
 func (db *DB) queryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if tx, ok := db.txFromContext(ctx); ok {
+		return tx.queryContext(ctx, query, args...)
+	}
 	return db.db.QueryContext(ctx, query, args...)
 }
 
 func (db *DB) execContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if tx, ok := db.txFromContext(ctx); ok {
+		return tx.execContext(ctx, query, args...)
+	}
 	return db.db.ExecContext(ctx, query, args...)
 }
 
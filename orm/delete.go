@@ -0,0 +1,137 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Deleter[T any] struct {
+	builder
+	sess  Session
+	val   *T
+	where []Predicate
+	hard  bool
+}
+
+func NewDeleter[T any](sess Session) *Deleter[T] {
+	c := sess.getCore()
+	return &Deleter[T]{
+		builder: builder{
+			core:   c,
+			quoter: c.dialect.quoter(),
+		},
+		sess: sess,
+	}
+}
+
+// Delete 指定要删除的实体
+// 如果没有调用 Where，那么按照实体的主键删除，模型没有主键的时候返回错误
+func (d *Deleter[T]) Delete(t *T) *Deleter[T] {
+	d.val = t
+	return d
+}
+
+// Where 指定删除条件
+// 不调用 Where 也没有指定实体代表删除整张表，可以配合 safedml 中间件禁止这种行为
+func (d *Deleter[T]) Where(ps ...Predicate) *Deleter[T] {
+	d.where = ps
+	return d
+}
+
+// HardDelete 真的删除数据
+// 模型有软删除字段的时候，默认只是把软删除字段设置为当前时间
+func (d *Deleter[T]) HardDelete() *Deleter[T] {
+	d.hard = true
+	return d
+}
+
+func (d *Deleter[T]) Build() (*Query, error) {
+	d.reset()
+	if d.model == nil {
+		var err error
+		d.model, err = d.r.Get(new(T))
+		if err != nil {
+			return nil, err
+		}
+	}
+	where := d.where
+	if len(where) == 0 && d.val != nil {
+		var err error
+		if where, err = d.primaryKeyWhere(d.val); err != nil {
+			return nil, err
+		}
+	}
+	if d.soft() {
+		// UPDATE xxx SET deleted_at = ? WHERE ... AND deleted_at IS NULL
+		// 已经删除的数据不会再更新删除时间
+		fd := d.model.SoftDelete
+		d.sb.WriteString("UPDATE ")
+		d.quote(d.model.TableName)
+		d.sb.WriteString(" SET ")
+		d.quote(fd.ColName)
+		d.sb.WriteByte('=')
+		d.writeArg(timeValue(fd, d.now()))
+		where = append(where[:len(where):len(where)], C(fd.GoName).IsNull())
+	} else {
+		d.sb.WriteString("DELETE FROM ")
+		d.quote(d.model.TableName)
+	}
+	if len(where) > 0 {
+		d.sb.WriteString(" WHERE ")
+		p := where[0]
+		for i := 1; i < len(where); i++ {
+			p = p.And(where[i])
+		}
+		if err := d.buildExpression(p); err != nil {
+			return nil, err
+		}
+	}
+	d.sb.WriteByte(';')
+	return &Query{
+		SQL:  d.sb.String(),
+		Args: d.args,
+	}, nil
+}
+
+func (d *Deleter[T]) Exec(ctx context.Context) Result {
+	var err error
+	d.model, err = d.r.Get(new(T))
+	if err != nil {
+		return Result{
+			err: err,
+		}
+	}
+	var res sql.Result
+	if d.model.Sharding != nil {
+		res, err = d.shardingExec(ctx)
+	} else {
+		res, err = d.exec(ctx)
+	}
+	return Result{
+		err: err,
+		res: res,
+	}
+}
+
+func (d *Deleter[T]) exec(ctx context.Context) (sql.Result, error) {
+	// 软删除实际上是 UPDATE
+	typ := "DELETE"
+	if d.soft() {
+		typ = "UPDATE"
+	}
+	res := exec(ctx, d.sess, d.core, &QueryContext{
+		Type:      typ,
+		Builder:   d,
+		Model:     d.model,
+		FullTable: len(d.where) == 0 && d.val == nil,
+	})
+	var sqlRes sql.Result
+	if res.Result != nil {
+		sqlRes = res.Result.(sql.Result)
+	}
+	return sqlRes, res.Err
+}
+
+func (d *Deleter[T]) soft() bool {
+	return d.model.SoftDelete != nil && !d.hard
+}
@@ -0,0 +1,228 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"scaffolding-go/orm/internal/errs"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type SoftDeleteModel struct {
+	Id        int64 `orm:"pk"`
+	Name      string
+	DeletedAt *time.Time `orm:"soft_delete"`
+}
+
+func TestDeleter_Build(t *testing.T) {
+	db := memoryDB(t)
+	now := time.UnixMilli(1700000000000)
+	db.now = func() time.Time {
+		return now
+	}
+	type MilliModel struct {
+		Id        int64
+		DeletedAt sql.NullInt64 `orm:"soft_delete"`
+	}
+	type PKModel struct {
+		TenantId int64 `orm:"pk"`
+		Id       int64 `orm:"pk,auto_increment"`
+		Name     string
+	}
+	testCases := []struct {
+		name      string
+		d         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "no where",
+			d:    NewDeleter[TestModel](db),
+			wantQuery: &Query{
+				SQL: "DELETE FROM `test_model`;",
+			},
+		},
+		{
+			name: "where",
+			d:    NewDeleter[TestModel](db).Where(C("Id").Eq(16)),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM `test_model` WHERE `id` = ?;",
+				Args: []any{16},
+			},
+		},
+		{
+			name: "multiple where",
+			d:    NewDeleter[TestModel](db).Where(C("Id").Eq(16), Not(C("FirstName").Eq("Tom"))),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM `test_model` WHERE (`id` = ?) AND ( NOT (`first_name` = ?));",
+				Args: []any{16, "Tom"},
+			},
+		},
+		{
+			name: "primary key",
+			d:    NewDeleter[PKModel](db).Delete(&PKModel{TenantId: 1, Id: 16, Name: "Tom"}),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM `p_k_model` WHERE (`tenant_id` = ?) AND (`id` = ?);",
+				Args: []any{int64(1), int64(16)},
+			},
+		},
+		{
+			name: "where over primary key",
+			d: NewDeleter[PKModel](db).Delete(&PKModel{TenantId: 1, Id: 16}).
+				Where(C("Name").Eq("Tom")),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM `p_k_model` WHERE `name` = ?;",
+				Args: []any{"Tom"},
+			},
+		},
+		{
+			name:    "entity without primary key",
+			d:       NewDeleter[TestModel](db).Delete(&TestModel{Id: 16}),
+			wantErr: errs.ErrNoPrimaryKey,
+		},
+		{
+			name: "soft delete",
+			d:    NewDeleter[SoftDeleteModel](db).Delete(&SoftDeleteModel{Id: 16}),
+			wantQuery: &Query{
+				SQL:  "UPDATE `soft_delete_model` SET `deleted_at`=? WHERE (`id` = ?) AND (`deleted_at` IS NULL);",
+				Args: []any{now, int64(16)},
+			},
+		},
+		{
+			name: "soft delete all",
+			d:    NewDeleter[SoftDeleteModel](db),
+			wantQuery: &Query{
+				SQL:  "UPDATE `soft_delete_model` SET `deleted_at`=? WHERE `deleted_at` IS NULL;",
+				Args: []any{now},
+			},
+		},
+		{
+			name: "soft delete timestamp",
+			d:    NewDeleter[MilliModel](db).Where(C("Id").Eq(16)),
+			wantQuery: &Query{
+				SQL:  "UPDATE `milli_model` SET `deleted_at`=? WHERE (`id` = ?) AND (`deleted_at` IS NULL);",
+				Args: []any{int64(1700000000000), 16},
+			},
+		},
+		{
+			name: "hard delete",
+			d:    NewDeleter[SoftDeleteModel](db).Where(C("Id").Eq(16)).HardDelete(),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM `soft_delete_model` WHERE `id` = ?;",
+				Args: []any{16},
+			},
+		},
+		{
+			name:    "invalid column",
+			d:       NewDeleter[TestModel](db).Where(C("Invalid").Eq(16)),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.d.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestDeleter_Exec(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	testCases := []struct {
+		name     string
+		d        func(sess Session) *Deleter[TestModel]
+		wantErr  error
+		affected int64
+	}{
+		{
+			name: "db error",
+			d: func(sess Session) *Deleter[TestModel] {
+				mock.ExpectExec("DELETE FROM .*").
+					WillReturnError(errors.New("db error"))
+				return NewDeleter[TestModel](sess).Where(C("Id").Eq(1))
+			},
+			wantErr: errors.New("db error"),
+		},
+		{
+			name: "exec",
+			d: func(sess Session) *Deleter[TestModel] {
+				mock.ExpectExec("DELETE FROM .*").
+					WillReturnResult(driver.RowsAffected(1))
+				return NewDeleter[TestModel](sess).Where(C("Id").Eq(1))
+			},
+			affected: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := tc.d(db).Exec(context.Background())
+			affected, err := res.RowsAffected()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.affected, affected)
+		})
+	}
+
+	// 在事务里面删除
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `test_model` WHERE `id` = ?;")).
+		WithArgs(1).WillReturnResult(driver.RowsAffected(1))
+	mock.ExpectCommit()
+	err = db.DoTx(context.Background(), func(ctx context.Context, tx *Tx) error {
+		return NewDeleter[TestModel](tx).Where(C("Id").Eq(1)).Exec(ctx).Err()
+	}, &sql.TxOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleter_SoftDelete(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithMiddleware(func(next Handler) Handler {
+		// 模拟 nodelete 和 safedml 中间件
+		return func(ctx context.Context, qc *QueryContext) *QueryResult {
+			if qc.Type == "DELETE" {
+				return &QueryResult{Err: errors.New("禁止 DELETE")}
+			}
+			if qc.FullTable {
+				return &QueryResult{Err: errors.New("禁止没有 WHERE")}
+			}
+			return next(ctx, qc)
+		}
+	}))
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `soft_delete_model` SET `deleted_at`=? "+
+		"WHERE (`id` = ?) AND (`deleted_at` IS NULL);")).
+		WithArgs(sqlmock.AnyArg(), 16).WillReturnResult(driver.RowsAffected(1))
+	affected, err := NewDeleter[SoftDeleteModel](db).Where(C("Id").Eq(16)).
+		Exec(context.Background()).RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	err = NewDeleter[SoftDeleteModel](db).Where(C("Id").Eq(16)).HardDelete().
+		Exec(context.Background()).Err()
+	assert.Equal(t, errors.New("禁止 DELETE"), err)
+
+	// 软删除自动加上的条件不算
+	err = NewDeleter[SoftDeleteModel](db).Exec(context.Background()).Err()
+	assert.Equal(t, errors.New("禁止没有 WHERE"), err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,12 +1,20 @@
 package orm
 
 import (
+	"database/sql"
+	"reflect"
 	"scaffolding-go/orm/internal/errs"
+	"scaffolding-go/orm/model"
+	"strconv"
+	"time"
 )
 
 var (
-	DialectMySQL  Dialect = mysqlDialect{}
-	DialectSQLite Dialect = sqliteDialect{}
+	// DialectStandardSQL 标准 SQL 方言，可以作为 NewDialect 的基础方言
+	DialectStandardSQL Dialect = standardSQL{}
+	DialectMySQL       Dialect = mysqlDialect{}
+	DialectSQLite      Dialect = sqliteDialect{}
+	DialectPostgreSQL  Dialect = postgresDialect{}
 )
 
 type Dialect interface {
@@ -15,19 +23,113 @@ type Dialect interface {
 	quoter() byte
 
 	buildUpsert(b *builder, upsert *Upsert) error
+
+	// buildLimit 构造分页部分，limit 和 offset 为 0 代表没有设置
+	buildLimit(b *builder, limit int, offset int) error
+
+	// placeholder 返回第 idx 个参数的占位符，idx 从 1 开始
+	// MySQL 是 ?，PostgreSQL 是 $1
+	placeholder(idx int) string
+
+	// supportReturning 是否支持 INSERT ... RETURNING
+	supportReturning() bool
+
+	// firstInsertId 根据 LastInsertId 计算一次插入多行时第一行的 id
+	// MySQL 返回的是第一行的 id，SQLite 返回的是最后一行的
+	firstInsertId(lastInsertId int64, rows int) int64
+
+	// columnType 返回字段在建表语句里面的类型，自增列会带上自增的修饰
+	columnType(fd *model.Field) (string, error)
+
+	// columnsQuery 返回查询表里面已有列名的 SQL，占位符统一使用 ?
+	// 表不存在的时候查询结果为空
+	columnsQuery(table string) (string, []any)
+
+	// indexesQuery 返回查询表里面已有索引名的 SQL，占位符统一使用 ?
+	// 返回空字符串代表不支持，AutoMigrate 就不会比较索引
+	indexesQuery(table string) (string, []any)
+
+	// compoundParens 组合查询的成员能不能用括号括起来
+	// 成员自己有 ORDER BY 或者 LIMIT 的时候必须括起来，SQLite 不支持
+	compoundParens() bool
+
+	// buildConcat 构造字符串拼接，参考 Concat
+	// SQLite 3.44 之前没有 CONCAT 函数，所以标准的方言用 ||
+	buildConcat(b *builder, args []Expression) error
 }
 
+// standardSQL 是 ANSI SQL 方言
+// 其它方言组合它，然后覆盖不一样的部分
 type standardSQL struct {
 }
 
+func (s standardSQL) placeholder(idx int) string {
+	return "?"
+}
+
+func (s standardSQL) supportReturning() bool {
+	return false
+}
+
+func (s standardSQL) firstInsertId(lastInsertId int64, rows int) int64 {
+	return lastInsertId
+}
+
+func (s standardSQL) columnType(fd *model.Field) (string, error) {
+	res, err := ansiColumnType(fd)
+	if err != nil {
+		return "", err
+	}
+	if fd.AutoIncrement {
+		res += " GENERATED BY DEFAULT AS IDENTITY"
+	}
+	return res, nil
+}
+
+func (s standardSQL) columnsQuery(table string) (string, []any) {
+	return "SELECT column_name FROM information_schema.columns WHERE table_name = ?", []any{table}
+}
+
+// indexesQuery 标准 SQL 没有定义索引的元数据
+func (s standardSQL) indexesQuery(table string) (string, []any) {
+	return "", nil
+}
+
+func (s standardSQL) compoundParens() bool {
+	return true
+}
+
+func (s standardSQL) buildConcat(b *builder, args []Expression) error {
+	b.sb.WriteByte('(')
+	if err := b.buildExpressions(args, " || "); err != nil {
+		return err
+	}
+	b.sb.WriteByte(')')
+	return nil
+}
+
 func (s standardSQL) quoter() byte {
-	//TODO implement me
-	panic("implement me")
+	return '"'
 }
 
+// buildUpsert 标准 SQL 只有 MERGE，语义和 upsert 差别太大，所以不支持
 func (s standardSQL) buildUpsert(b *builder, upsert *Upsert) error {
-	//TODO implement me
-	panic("implement me")
+	return errs.ErrUnsupportedUpsert
+}
+
+// buildLimit 使用 SQL:2008 的 OFFSET ... ROWS FETCH FIRST ... ROWS ONLY
+func (s standardSQL) buildLimit(b *builder, limit int, offset int) error {
+	if offset > 0 {
+		b.sb.WriteString(" OFFSET ")
+		b.writeArg(offset)
+		b.sb.WriteString(" ROWS")
+	}
+	if limit > 0 {
+		b.sb.WriteString(" FETCH FIRST ")
+		b.writeArg(limit)
+		b.sb.WriteString(" ROWS ONLY")
+	}
+	return nil
 }
 
 type mysqlDialect struct {
@@ -43,30 +145,95 @@ func (s mysqlDialect) buildUpsert(b *builder, upsert *Upsert) error {
 		if idx > 0 {
 			b.sb.WriteByte(',')
 		}
-		switch a := assign.(type) {
-		case Assignment:
-			fd, ok := b.model.FieldMap[a.col]
-			// 字段不对，或者说列不对
-			if !ok {
-				return errs.NewErrUnknownField(a.col)
-			}
-			b.quote(fd.ColName)
-			b.sb.WriteString("=?")
-			b.addArg(a.val)
-		case Column:
-			fd, ok := b.model.FieldMap[a.name]
-			// 字段不对，或者说列不对
-			if !ok {
-				return errs.NewErrUnknownField(a.name)
+		err := b.buildUpsertAssign(assign, func(col string) string {
+			return "VALUES(" + col + ")"
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s mysqlDialect) columnType(fd *model.Field) (string, error) {
+	typ := columnGoType(fd.Typ)
+	var res string
+	switch typ.Kind() {
+	case reflect.Bool:
+		res = "TINYINT(1)"
+	case reflect.Int8:
+		res = "TINYINT"
+	case reflect.Uint8:
+		res = "TINYINT UNSIGNED"
+	case reflect.Int16:
+		res = "SMALLINT"
+	case reflect.Uint16:
+		res = "SMALLINT UNSIGNED"
+	case reflect.Int32:
+		res = "INT"
+	case reflect.Uint32:
+		res = "INT UNSIGNED"
+	case reflect.Int, reflect.Int64:
+		res = "BIGINT"
+	case reflect.Uint, reflect.Uint64:
+		res = "BIGINT UNSIGNED"
+	case reflect.Float32:
+		res = "FLOAT"
+	case reflect.Float64:
+		res = "DOUBLE"
+	case reflect.String:
+		res = "VARCHAR(" + strconv.Itoa(columnSize(fd)) + ")"
+	default:
+		switch typ {
+		case bytesType:
+			res = "BLOB"
+			if fd.Size > 0 {
+				res = "VARBINARY(" + strconv.Itoa(fd.Size) + ")"
 			}
-			b.quote(fd.ColName)
-			b.sb.WriteString("=VALUES(")
-			b.quote(fd.ColName)
-			b.sb.WriteByte(')')
+		case timeType:
+			res = "DATETIME"
 		default:
-			return errs.NewErrUnsupportedAssignable(a)
+			return "", errs.NewErrUnsupportedColumnType(fd.GoName, fd.Typ)
 		}
 	}
+	if fd.AutoIncrement {
+		res += " AUTO_INCREMENT"
+	}
+	return res, nil
+}
+
+func (s mysqlDialect) columnsQuery(table string) (string, []any) {
+	return "SELECT COLUMN_NAME FROM information_schema.COLUMNS " +
+		"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", []any{table}
+}
+
+func (s mysqlDialect) indexesQuery(table string) (string, []any) {
+	return "SELECT DISTINCT INDEX_NAME FROM information_schema.STATISTICS " +
+		"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", []any{table}
+}
+
+// buildConcat MySQL 默认把 || 当成 OR
+func (s mysqlDialect) buildConcat(b *builder, args []Expression) error {
+	b.sb.WriteString("CONCAT(")
+	if err := b.buildExpressions(args, ","); err != nil {
+		return err
+	}
+	b.sb.WriteByte(')')
+	return nil
+}
+
+func (s mysqlDialect) buildLimit(b *builder, limit int, offset int) error {
+	b.sb.WriteString(" LIMIT ")
+	if limit > 0 {
+		b.writeArg(limit)
+	} else {
+		// MySQL 只有 OFFSET 是非法的，官方文档推荐用一个足够大的数字代替
+		b.sb.WriteString("18446744073709551615")
+	}
+	if offset > 0 {
+		b.sb.WriteString(" OFFSET ")
+		b.writeArg(offset)
+	}
 	return nil
 }
 
@@ -78,7 +245,67 @@ func (s sqliteDialect) quoter() byte {
 	return '`'
 }
 
+func (s sqliteDialect) firstInsertId(lastInsertId int64, rows int) int64 {
+	return lastInsertId - int64(rows) + 1
+}
+
 func (s sqliteDialect) buildUpsert(b *builder, upsert *Upsert) error {
+	return buildOnConflict(b, upsert, "excluded")
+}
+
+// columnType SQLite 只有几种类型亲和性
+// 单独的 INTEGER 主键就是 rowid 的别名，所以自增列不需要额外的修饰
+func (s sqliteDialect) columnType(fd *model.Field) (string, error) {
+	typ := columnGoType(fd.Typ)
+	switch typ.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "INTEGER", nil
+	case reflect.Float32, reflect.Float64:
+		return "REAL", nil
+	case reflect.String:
+		return "TEXT", nil
+	}
+	switch typ {
+	case bytesType:
+		return "BLOB", nil
+	case timeType:
+		return "DATETIME", nil
+	default:
+		return "", errs.NewErrUnsupportedColumnType(fd.GoName, fd.Typ)
+	}
+}
+
+func (s sqliteDialect) columnsQuery(table string) (string, []any) {
+	return "SELECT name FROM pragma_table_info(?)", []any{table}
+}
+
+func (s sqliteDialect) indexesQuery(table string) (string, []any) {
+	return "SELECT name FROM pragma_index_list(?)", []any{table}
+}
+
+func (s sqliteDialect) compoundParens() bool {
+	return false
+}
+
+func (s sqliteDialect) buildLimit(b *builder, limit int, offset int) error {
+	b.sb.WriteString(" LIMIT ")
+	if limit > 0 {
+		b.writeArg(limit)
+	} else {
+		// SQLite 用负数代表没有上限
+		b.sb.WriteString("-1")
+	}
+	if offset > 0 {
+		b.sb.WriteString(" OFFSET ")
+		b.writeArg(offset)
+	}
+	return nil
+}
+
+// buildOnConflict 构造 ON CONFLICT(...) DO UPDATE SET 形态的 upsert
+// SQLite 和 PostgreSQL 都是这种语法，excluded 代表冲突时准备插入的那一行
+func buildOnConflict(b *builder, upsert *Upsert, excluded string) error {
 	b.sb.WriteString(" ON CONFLICT(")
 	for i, col := range upsert.conflictColumns {
 		if i > 0 {
@@ -94,28 +321,368 @@ func (s sqliteDialect) buildUpsert(b *builder, upsert *Upsert) error {
 		if idx > 0 {
 			b.sb.WriteByte(',')
 		}
-		switch a := assign.(type) {
-		case Assignment:
-			fd, ok := b.model.FieldMap[a.col]
-			// 字段不对，或者说列不对
-			if !ok {
-				return errs.NewErrUnknownField(a.col)
-			}
-			b.quote(fd.ColName)
-			b.sb.WriteString("=?")
-			b.addArg(a.val)
-		case Column:
-			fd, ok := b.model.FieldMap[a.name]
-			// 字段不对，或者说列不对
-			if !ok {
-				return errs.NewErrUnknownField(a.name)
-			}
-			b.quote(fd.ColName)
-			b.sb.WriteString("=excluded.")
-			b.quote(fd.ColName)
-		default:
-			return errs.NewErrUnsupportedAssignable(a)
+		err := b.buildUpsertAssign(assign, func(col string) string {
+			return excluded + "." + col
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildUpsertAssign 构造 upsert 里面的 col=val
+// C("Age") 代表更新为准备插入的值，inserted 根据加了引号的列名返回对应的表达式
+func (b *builder) buildUpsertAssign(assign Assignable, inserted func(col string) string) error {
+	switch a := assign.(type) {
+	case Assignment:
+		fd, ok := b.model.FieldMap[a.col]
+		// 字段不对，或者说列不对
+		if !ok {
+			return errs.NewErrUnknownField(a.col)
+		}
+		b.quote(fd.ColName)
+		b.sb.WriteByte('=')
+		return b.buildExpression(valueOf(a.val))
+	case Column:
+		fd, ok := b.model.FieldMap[a.name]
+		// 字段不对，或者说列不对
+		if !ok {
+			return errs.NewErrUnknownField(a.name)
+		}
+		b.quote(fd.ColName)
+		b.sb.WriteByte('=')
+		col := string(b.quoter) + fd.ColName + string(b.quoter)
+		b.sb.WriteString(inserted(col))
+		return nil
+	default:
+		return errs.NewErrUnsupportedAssignable(a)
+	}
+}
+
+type postgresDialect struct {
+	standardSQL
+}
+
+func (s postgresDialect) quoter() byte {
+	return '"'
+}
+
+func (s postgresDialect) placeholder(idx int) string {
+	return "$" + strconv.Itoa(idx)
+}
+
+func (s postgresDialect) supportReturning() bool {
+	return true
+}
+
+func (s postgresDialect) buildUpsert(b *builder, upsert *Upsert) error {
+	// PostgreSQL 的 DO UPDATE 必须指定冲突的列
+	if len(upsert.conflictColumns) == 0 {
+		return errs.ErrUpsertWithoutConflictColumns
+	}
+	return buildOnConflict(b, upsert, "EXCLUDED")
+}
+
+func (s postgresDialect) columnType(fd *model.Field) (string, error) {
+	typ := columnGoType(fd.Typ)
+	var res string
+	switch {
+	case typ.Kind() == reflect.String && fd.Size == 0:
+		res = "TEXT"
+	case typ == bytesType:
+		res = "BYTEA"
+	default:
+		var err error
+		if res, err = ansiColumnType(fd); err != nil {
+			return "", err
 		}
 	}
+	if !fd.AutoIncrement {
+		return res, nil
+	}
+	// PostgreSQL 用 SERIAL 系列的类型代表自增
+	switch res {
+	case "SMALLINT":
+		return "SMALLSERIAL", nil
+	case "INTEGER":
+		return "SERIAL", nil
+	default:
+		return "BIGSERIAL", nil
+	}
+}
+
+func (s postgresDialect) columnsQuery(table string) (string, []any) {
+	return "SELECT column_name FROM information_schema.columns " +
+		"WHERE table_schema = current_schema() AND table_name = ?", []any{table}
+}
+
+func (s postgresDialect) indexesQuery(table string) (string, []any) {
+	return "SELECT indexname FROM pg_indexes " +
+		"WHERE schemaname = current_schema() AND tablename = ?", []any{table}
+}
+
+func (s postgresDialect) buildLimit(b *builder, limit int, offset int) error {
+	// PostgreSQL 允许单独使用 OFFSET
+	if limit > 0 {
+		b.sb.WriteString(" LIMIT ")
+		b.writeArg(limit)
+	}
+	if offset > 0 {
+		b.sb.WriteString(" OFFSET ")
+		b.writeArg(offset)
+	}
 	return nil
 }
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+
+	// nullTypes sql.NullXXX 按照里面的类型建列
+	nullTypes = map[reflect.Type]reflect.Type{
+		reflect.TypeOf(sql.NullString{}):  reflect.TypeOf(""),
+		reflect.TypeOf(sql.NullBool{}):    reflect.TypeOf(false),
+		reflect.TypeOf(sql.NullByte{}):    reflect.TypeOf(byte(0)),
+		reflect.TypeOf(sql.NullInt16{}):   reflect.TypeOf(int16(0)),
+		reflect.TypeOf(sql.NullInt32{}):   reflect.TypeOf(int32(0)),
+		reflect.TypeOf(sql.NullInt64{}):   reflect.TypeOf(int64(0)),
+		reflect.TypeOf(sql.NullFloat64{}): reflect.TypeOf(float64(0)),
+		reflect.TypeOf(sql.NullTime{}):    timeType,
+	}
+)
+
+// columnGoType 把字段类型归一化，指针和 sql.NullXXX 都按照里面的类型处理
+func columnGoType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if res, ok := nullTypes[typ]; ok {
+		return res
+	}
+	return typ
+}
+
+// columnSize 没有指定 size 的字符串默认 255
+func columnSize(fd *model.Field) int {
+	if fd.Size > 0 {
+		return fd.Size
+	}
+	return 255
+}
+
+// ansiColumnType 返回 SQL 标准里面的类型
+// 标准里面没有无符号整数，所以使用范围更大的类型
+func ansiColumnType(fd *model.Field) (string, error) {
+	typ := columnGoType(fd.Typ)
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "BOOLEAN", nil
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "SMALLINT", nil
+	case reflect.Int32, reflect.Uint16:
+		return "INTEGER", nil
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "BIGINT", nil
+	case reflect.Uint, reflect.Uint64:
+		return "NUMERIC(20)", nil
+	case reflect.Float32:
+		return "REAL", nil
+	case reflect.Float64:
+		return "DOUBLE PRECISION", nil
+	case reflect.String:
+		return "VARCHAR(" + strconv.Itoa(columnSize(fd)) + ")", nil
+	}
+	switch typ {
+	case bytesType:
+		return "BLOB", nil
+	case timeType:
+		return "TIMESTAMP", nil
+	default:
+		return "", errs.NewErrUnsupportedColumnType(fd.GoName, fd.Typ)
+	}
+}
+
+type DialectOption func(d *customDialect)
+
+// customDialect 在 base 的基础上覆盖部分行为
+// 没有覆盖的部分都交给 base
+type customDialect struct {
+	Dialect
+	quote         byte
+	placeholderFn func(idx int) string
+	returning     *bool
+	limitFn       func(b *SQLBuilder, limit int, offset int) error
+	upsertFn      func(b *SQLBuilder, upsert *Upsert) error
+	columnTypeFn  func(fd *model.Field) (string, error)
+	columnsFn     func(table string) (string, []any)
+	indexesFn     func(table string) (string, []any)
+}
+
+// NewDialect 在一个已有方言的基础上定制新的方言，然后通过 DBWithDialect 使用
+// 例如 TiDB 兼容 MySQL 协议，可以直接用 NewDialect(DialectMySQL)
+// 分页、upsert、列类型这些不一样的地方用 DialectWithLimit 之类的选项覆盖
+//
+//	dialect := NewDialect(DialectStandardSQL, DialectWithPlaceholder(func(idx int) string {
+//		return ":" + strconv.Itoa(idx)
+//	}))
+//	db, err := Open("dm", dsn, DBWithDialect(dialect))
+func NewDialect(base Dialect, opts ...DialectOption) Dialect {
+	res := &customDialect{
+		Dialect: base,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// DialectWithQuoter 指定引号
+func DialectWithQuoter(quote byte) DialectOption {
+	return func(d *customDialect) {
+		d.quote = quote
+	}
+}
+
+// DialectWithPlaceholder 指定占位符，idx 从 1 开始
+func DialectWithPlaceholder(fn func(idx int) string) DialectOption {
+	return func(d *customDialect) {
+		d.placeholderFn = fn
+	}
+}
+
+// DialectWithReturning 指定是否支持 INSERT ... RETURNING
+func DialectWithReturning(support bool) DialectOption {
+	return func(d *customDialect) {
+		d.returning = &support
+	}
+}
+
+// DialectWithLimit 指定分页部分的构造，limit 和 offset 为 0 代表没有设置
+//
+//	DialectWithLimit(func(b *SQLBuilder, limit int, offset int) error {
+//		if limit > 0 {
+//			b.WriteString(" TOP ")
+//			b.Arg(limit)
+//		}
+//		return nil
+//	})
+func DialectWithLimit(fn func(b *SQLBuilder, limit int, offset int) error) DialectOption {
+	return func(d *customDialect) {
+		d.limitFn = fn
+	}
+}
+
+// DialectWithUpsert 指定 upsert 部分的构造，也就是 VALUES 后面的部分
+// 不支持 upsert 的数据库可以直接返回错误
+func DialectWithUpsert(fn func(b *SQLBuilder, upsert *Upsert) error) DialectOption {
+	return func(d *customDialect) {
+		d.upsertFn = fn
+	}
+}
+
+// DialectWithColumnType 指定字段在建表语句里面的类型
+// 返回空字符串代表使用基础方言的类型，这样可以只覆盖部分类型
+func DialectWithColumnType(fn func(fd *model.Field) (string, error)) DialectOption {
+	return func(d *customDialect) {
+		d.columnTypeFn = fn
+	}
+}
+
+// DialectWithIntrospection 指定 AutoMigrate 查询已有列名和索引名的 SQL，占位符统一使用 ?
+// 传入 nil 代表使用基础方言的，indexes 返回空字符串代表不比较索引
+func DialectWithIntrospection(columns, indexes func(table string) (string, []any)) DialectOption {
+	return func(d *customDialect) {
+		d.columnsFn = columns
+		d.indexesFn = indexes
+	}
+}
+
+func (d *customDialect) quoter() byte {
+	if d.quote != 0 {
+		return d.quote
+	}
+	return d.Dialect.quoter()
+}
+
+func (d *customDialect) placeholder(idx int) string {
+	if d.placeholderFn != nil {
+		return d.placeholderFn(idx)
+	}
+	return d.Dialect.placeholder(idx)
+}
+
+func (d *customDialect) supportReturning() bool {
+	if d.returning != nil {
+		return *d.returning
+	}
+	return d.Dialect.supportReturning()
+}
+
+func (d *customDialect) buildLimit(b *builder, limit int, offset int) error {
+	if d.limitFn != nil {
+		return d.limitFn(&SQLBuilder{b: b}, limit, offset)
+	}
+	return d.Dialect.buildLimit(b, limit, offset)
+}
+
+func (d *customDialect) buildUpsert(b *builder, upsert *Upsert) error {
+	if d.upsertFn != nil {
+		return d.upsertFn(&SQLBuilder{b: b}, upsert)
+	}
+	return d.Dialect.buildUpsert(b, upsert)
+}
+
+func (d *customDialect) columnType(fd *model.Field) (string, error) {
+	if d.columnTypeFn != nil {
+		typ, err := d.columnTypeFn(fd)
+		if err != nil || typ != "" {
+			return typ, err
+		}
+	}
+	return d.Dialect.columnType(fd)
+}
+
+func (d *customDialect) columnsQuery(table string) (string, []any) {
+	if d.columnsFn != nil {
+		return d.columnsFn(table)
+	}
+	return d.Dialect.columnsQuery(table)
+}
+
+func (d *customDialect) indexesQuery(table string) (string, []any) {
+	if d.indexesFn != nil {
+		return d.indexesFn(table)
+	}
+	return d.Dialect.indexesQuery(table)
+}
+
+// SQLBuilder 给自定义方言构造 SQL 片段用，参考 DialectWithLimit 和 DialectWithUpsert
+type SQLBuilder struct {
+	b *builder
+}
+
+func (s *SQLBuilder) WriteString(str string) {
+	s.b.sb.WriteString(str)
+}
+
+// Quote 用方言的引号把名字括起来
+func (s *SQLBuilder) Quote(name string) {
+	s.b.quote(name)
+}
+
+// Column 写入字段对应的列名
+func (s *SQLBuilder) Column(field string) error {
+	return s.b.buildColumn(Column{name: field})
+}
+
+// Arg 写入占位符，并且记录对应的参数
+func (s *SQLBuilder) Arg(val any) {
+	s.b.writeArg(val)
+}
+
+// Assign 写入 upsert 里面的 col=val
+// C("Age") 代表更新为准备插入的值，inserted 根据加了引号的列名返回对应的表达式，例如 EXCLUDED."age"
+func (s *SQLBuilder) Assign(assign Assignable, inserted func(col string) string) error {
+	return s.b.buildUpsertAssign(assign, inserted)
+}
@@ -0,0 +1,384 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"scaffolding-go/orm/internal/errs"
+	"scaffolding-go/orm/model"
+	"strconv"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialect_PostgreSQL(t *testing.T) {
+	db := memoryDB(t, DBWithDialect(DialectPostgreSQL))
+	type Order struct {
+		Id     int64
+		UserId int64
+	}
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "select",
+			q: NewSelector[TestModel](db).Select(C("Id"), C("Age").Add(1).As("next_age")).
+				Where(C("Age").GT(18), C("Id").In(1, 2), Raw("first_name LIKE ?", "T%").AsPredicate()).
+				OrderBy(Desc("Id")).Limit(10).Offset(20),
+			wantQuery: &Query{
+				SQL: `SELECT "id","age" + $1 AS "next_age" FROM "test_model" ` +
+					`WHERE (("age" > $2) AND ("id" IN ($3,$4))) AND ((first_name LIKE $5)) ` +
+					`ORDER BY "id" DESC LIMIT $6 OFFSET $7;`,
+				Args: []any{1, 18, 1, 2, "T%", 10, 20},
+			},
+		},
+		{
+			name: "offset only",
+			q:    NewSelector[TestModel](db).Offset(20),
+			wantQuery: &Query{
+				SQL:  `SELECT * FROM "test_model" OFFSET $1;`,
+				Args: []any{20},
+			},
+		},
+		{
+			name: "subquery",
+			q: func() QueryBuilder {
+				sub := NewSelector[Order](db).Select(C("UserId")).
+					Where(C("Id").GT(100)).AsSubquery("sub")
+				return NewSelector[TestModel](db).Where(C("Age").GT(18), C("Id").InQuery(sub), C("Age").LT(30))
+			}(),
+			wantQuery: &Query{
+				SQL: `SELECT * FROM "test_model" WHERE (("age" > $1) AND ` +
+					`("id" IN (SELECT "user_id" FROM "order" WHERE "id" > $2))) AND ("age" < $3);`,
+				Args: []any{18, 100, 30},
+			},
+		},
+		{
+			name: "union",
+			q: Union(
+				NewSelector[TestModel](db).Where(C("Id").Eq(1)),
+				NewSelector[TestModel](db).Where(C("Id").Eq(2)),
+			).All().Limit(10),
+			wantQuery: &Query{
+				SQL: `SELECT * FROM "test_model" WHERE "id" = $1 UNION ALL ` +
+					`SELECT * FROM "test_model" WHERE "id" = $2 LIMIT $3;`,
+				Args: []any{1, 2, 10},
+			},
+		},
+		{
+			name: "insert upsert returning",
+			q: NewInserter[TestModel](db).Values(&TestModel{Id: 12, FirstName: "Tom", Age: 18}).
+				Columns("Id", "FirstName", "Age").
+				OnDuplicateKey().ConflictColumns("Id").
+				Update(Assign("FirstName", "Bob"), C("Age")).Returning("Id"),
+			wantQuery: &Query{
+				SQL: `INSERT INTO "test_model"("id","first_name","age") VALUES ($1,$2,$3) ` +
+					`ON CONFLICT("id") DO UPDATE SET "first_name"=$4,"age"=EXCLUDED."age" RETURNING "id";`,
+				Args: []any{int64(12), "Tom", int8(18), "Bob"},
+			},
+		},
+		{
+			name: "upsert without conflict columns",
+			q: NewInserter[TestModel](db).Values(&TestModel{Id: 12}).
+				OnDuplicateKey().Update(C("Age")),
+			wantErr: errs.ErrUpsertWithoutConflictColumns,
+		},
+		{
+			name: "update",
+			q: NewUpdater[TestModel](db).Set(Assign("Age", C("Age").Add(1)), Assign("FirstName", "Tom")).
+				Where(C("Id").Eq(12)),
+			wantQuery: &Query{
+				SQL:  `UPDATE "test_model" SET "age"="age" + $1,"first_name"=$2 WHERE "id" = $3;`,
+				Args: []any{1, "Tom", 12},
+			},
+		},
+		{
+			name: "delete",
+			q:    NewDeleter[TestModel](db).Where(C("Id").Between(1, 10)),
+			wantQuery: &Query{
+				SQL:  `DELETE FROM "test_model" WHERE "id" BETWEEN $1 AND $2;`,
+				Args: []any{1, 10},
+			},
+		},
+		{
+			name: "mysql returning",
+			q: NewInserter[TestModel](memoryDB(t)).Values(&TestModel{Id: 12}).
+				Returning("Id"),
+			wantErr: errs.ErrUnsupportedReturning,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+
+	// 作为子查询或者组合查询的一部分之后，单独构造仍然从 $1 开始
+	sub := NewSelector[TestModel](db).Select(C("Id")).Where(C("Age").GT(18))
+	_, err := NewSelector[TestModel](db).Where(C("Age").LT(30), C("Id").InQuery(sub.AsSubquery("sub"))).Build()
+	require.NoError(t, err)
+	_, err = Union(NewSelector[TestModel](db).Select(C("Id")).Where(C("Id").Eq(1)), sub).Build()
+	require.NoError(t, err)
+	q, err := sub.Build()
+	require.NoError(t, err)
+	assert.Equal(t, &Query{
+		SQL:  `SELECT "id" FROM "test_model" WHERE "age" > $1;`,
+		Args: []any{18},
+	}, q)
+}
+
+func TestInserter_Returning(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithDialect(DialectPostgreSQL))
+	require.NoError(t, err)
+
+	query := `INSERT INTO "test_model"("first_name","age") VALUES ($1,$2),($3,$4) RETURNING "id";`
+	mock.ExpectQuery(query).WithArgs("Tom", int8(18), "Bob", int8(20)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7).AddRow(8))
+	mock.ExpectQuery(query).WillReturnError(errors.New("db error"))
+
+	u1 := &TestModel{FirstName: "Tom", Age: 18}
+	u2 := &TestModel{FirstName: "Bob", Age: 20}
+	res := NewInserter[TestModel](db).Values(u1, u2).
+		Columns("FirstName", "Age").Returning("Id").Exec(context.Background())
+	require.NoError(t, res.Err())
+	affected, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+	assert.Equal(t, &TestModel{Id: 7, FirstName: "Tom", Age: 18}, u1)
+	assert.Equal(t, &TestModel{Id: 8, FirstName: "Bob", Age: 20}, u2)
+
+	res = NewInserter[TestModel](db).Values(&TestModel{}, &TestModel{}).
+		Columns("FirstName", "Age").Returning("Id").Exec(context.Background())
+	assert.Equal(t, errors.New("db error"), res.Err())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDialect_StandardSQL(t *testing.T) {
+	db := memoryDB(t, DBWithDialect(DialectStandardSQL))
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "limit offset",
+			q:    NewSelector[TestModel](db).Where(C("Id").Eq(12)).Limit(10).Offset(20),
+			wantQuery: &Query{
+				SQL:  `SELECT * FROM "test_model" WHERE "id" = ? OFFSET ? ROWS FETCH FIRST ? ROWS ONLY;`,
+				Args: []any{12, 20, 10},
+			},
+		},
+		{
+			name: "limit only",
+			q:    NewSelector[TestModel](db).Limit(10),
+			wantQuery: &Query{
+				SQL:  `SELECT * FROM "test_model" FETCH FIRST ? ROWS ONLY;`,
+				Args: []any{10},
+			},
+		},
+		{
+			name: "offset only",
+			q:    NewSelector[TestModel](db).Offset(20),
+			wantQuery: &Query{
+				SQL:  `SELECT * FROM "test_model" OFFSET ? ROWS;`,
+				Args: []any{20},
+			},
+		},
+		{
+			name: "concat",
+			q: NewSelector[TestModel](db).Select(Concat(C("FirstName"), " ", C("LastName")).As("name")).
+				Where(Concat(C("FirstName"), "!").Eq("Tom!")),
+			wantQuery: &Query{
+				SQL:  `SELECT ("first_name" || ? || "last_name") AS "name" FROM "test_model" WHERE ("first_name" || ?) = ?;`,
+				Args: []any{" ", "!", "Tom!"},
+			},
+		},
+		{
+			name: "upsert",
+			q: NewInserter[TestModel](db).Values(&TestModel{Id: 12}).
+				OnDuplicateKey().Update(C("Age")),
+			wantErr: errs.ErrUnsupportedUpsert,
+		},
+		{
+			name: "returning",
+			q: NewInserter[TestModel](db).Values(&TestModel{Id: 12}).
+				Returning("Id"),
+			wantErr: errs.ErrUnsupportedReturning,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestNewDialect(t *testing.T) {
+	oracle := NewDialect(DialectStandardSQL,
+		DialectWithPlaceholder(func(idx int) string {
+			return ":" + strconv.Itoa(idx)
+		}), DialectWithReturning(true))
+	tidb := NewDialect(DialectMySQL)
+	clickhouse := NewDialect(DialectMySQL, DialectWithQuoter('"'))
+	custom := NewDialect(DialectMySQL,
+		DialectWithLimit(func(b *SQLBuilder, limit int, offset int) error {
+			if limit > 0 {
+				b.WriteString(" LIMIT ")
+				b.Arg(limit)
+			}
+			if offset > 0 {
+				b.WriteString(" SKIP ")
+				b.Arg(offset)
+			}
+			return nil
+		}),
+		DialectWithUpsert(func(b *SQLBuilder, upsert *Upsert) error {
+			b.WriteString(" ON CONFLICT(")
+			for i, col := range upsert.ConflictColumns() {
+				if i > 0 {
+					b.WriteString(",")
+				}
+				if err := b.Column(col); err != nil {
+					return err
+				}
+			}
+			b.WriteString(") DO UPDATE SET ")
+			for i, assign := range upsert.Assigns() {
+				if i > 0 {
+					b.WriteString(",")
+				}
+				err := b.Assign(assign, func(col string) string {
+					return "NEW." + col
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "placeholder",
+			q: NewSelector[TestModel](memoryDB(t, DBWithDialect(oracle))).
+				Where(C("Id").Eq(12)).Limit(10),
+			wantQuery: &Query{
+				SQL:  `SELECT * FROM "test_model" WHERE "id" = :1 FETCH FIRST :2 ROWS ONLY;`,
+				Args: []any{12, 10},
+			},
+		},
+		{
+			name: "returning",
+			q: NewInserter[TestModel](memoryDB(t, DBWithDialect(oracle))).
+				Values(&TestModel{Id: 12}).Columns("Id").Returning("Id"),
+			wantQuery: &Query{
+				SQL:  `INSERT INTO "test_model"("id") VALUES (:1) RETURNING "id";`,
+				Args: []any{int64(12)},
+			},
+		},
+		{
+			name: "inherit",
+			q: NewInserter[TestModel](memoryDB(t, DBWithDialect(tidb))).
+				Values(&TestModel{Id: 12}).Columns("Id").
+				OnDuplicateKey().Update(C("Id")),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO `test_model`(`id`) VALUES (?) ON DUPLICATE KEY UPDATE `id`=VALUES(`id`);",
+				Args: []any{int64(12)},
+			},
+		},
+		{
+			name: "quoter",
+			q: NewSelector[TestModel](memoryDB(t, DBWithDialect(clickhouse))).
+				Where(C("Id").Eq(12)).Offset(20),
+			wantQuery: &Query{
+				SQL:  `SELECT * FROM "test_model" WHERE "id" = ? LIMIT 18446744073709551615 OFFSET ?;`,
+				Args: []any{12, 20},
+			},
+		},
+		{
+			name: "limit hook",
+			q: NewSelector[TestModel](memoryDB(t, DBWithDialect(custom))).
+				Where(C("Id").Eq(12)).Limit(10).Offset(20),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `id` = ? LIMIT ? SKIP ?;",
+				Args: []any{12, 10, 20},
+			},
+		},
+		{
+			name: "upsert hook",
+			q: NewInserter[TestModel](memoryDB(t, DBWithDialect(custom))).
+				Values(&TestModel{Id: 12, Age: 18}).Columns("Id", "Age").
+				OnDuplicateKey().ConflictColumns("Id").Update(C("Age"), Assign("FirstName", "Tom")),
+			wantQuery: &Query{
+				SQL: "INSERT INTO `test_model`(`id`,`age`) VALUES (?,?) " +
+					"ON CONFLICT(`id`) DO UPDATE SET `age`=NEW.`age`,`first_name`=?;",
+				Args: []any{int64(12), int8(18), "Tom"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestNewDialect_Migrate(t *testing.T) {
+	r := model.NewRegistry()
+	m, err := r.Get(&TestModel{})
+	require.NoError(t, err)
+	dialect := NewDialect(DialectMySQL,
+		DialectWithColumnType(func(fd *model.Field) (string, error) {
+			if fd.GoName == "Age" {
+				return "UInt8", nil
+			}
+			return "", nil
+		}),
+		DialectWithIntrospection(func(table string) (string, []any) {
+			return "SELECT name FROM system.columns WHERE table = ?", []any{table}
+		}, nil))
+
+	// 只覆盖部分字段的类型
+	typ, err := dialect.columnType(m.FieldMap["Age"])
+	require.NoError(t, err)
+	assert.Equal(t, "UInt8", typ)
+	typ, err = dialect.columnType(m.FieldMap["FirstName"])
+	require.NoError(t, err)
+	want, err := DialectMySQL.columnType(m.FieldMap["FirstName"])
+	require.NoError(t, err)
+	assert.Equal(t, want, typ)
+
+	query, args := dialect.columnsQuery("test_model")
+	assert.Equal(t, "SELECT name FROM system.columns WHERE table = ?", query)
+	assert.Equal(t, []any{"test_model"}, args)
+	query, _ = dialect.indexesQuery("test_model")
+	wantQuery, _ := DialectMySQL.indexesQuery("test_model")
+	assert.Equal(t, wantQuery, query)
+}
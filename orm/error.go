@@ -2,5 +2,10 @@ package orm
 
 import "scaffolding-go/orm/internal/errs"
 
-// ErrNoRows 通过别名形式将内部错误，暴露在外面
-var ErrNoRows = errs.ErrNoRows
+var (
+	// ErrNoRows 通过别名形式将内部错误，暴露在外面
+	ErrNoRows = errs.ErrNoRows
+	// ErrOptimisticLockConflict 按照版本号更新，但是没有更新到数据
+	// 说明数据已经被别人修改了，可以重新查询之后重试
+	ErrOptimisticLockConflict = errs.ErrOptimisticLockConflict
+)
@@ -26,3 +26,107 @@ func Raw(expr string, args ...any) RawExpr {
 		args: args,
 	}
 }
+
+// MathExpr 代表算术表达式
+// 例如 C("Age").Add(1)
+// 可以用在 SELECT、WHERE 以及 SET 部分
+type MathExpr struct {
+	left  Expression
+	op    op
+	right Expression
+	alias string
+}
+
+func (m MathExpr) expr()       {}
+func (m MathExpr) selectable() {}
+
+// As 在 SELECT 部分使用的别名
+func (m MathExpr) As(alias string) MathExpr {
+	return MathExpr{
+		left:  m.left,
+		op:    m.op,
+		right: m.right,
+		alias: alias,
+	}
+}
+
+func (m MathExpr) Add(val any) MathExpr {
+	return MathExpr{
+		left:  m,
+		op:    opAdd,
+		right: valueOf(val),
+	}
+}
+
+func (m MathExpr) Sub(val any) MathExpr {
+	return MathExpr{
+		left:  m,
+		op:    opSub,
+		right: valueOf(val),
+	}
+}
+
+func (m MathExpr) Multi(val any) MathExpr {
+	return MathExpr{
+		left:  m,
+		op:    opMulti,
+		right: valueOf(val),
+	}
+}
+
+func (m MathExpr) Div(val any) MathExpr {
+	return MathExpr{
+		left:  m,
+		op:    opDiv,
+		right: valueOf(val),
+	}
+}
+
+// Eq 例如 C("Age").Add(1).Eq(18)
+func (m MathExpr) Eq(arg any) Predicate {
+	return Predicate{
+		left:  m,
+		op:    opEq,
+		right: valueOf(arg),
+	}
+}
+
+func (m MathExpr) NEQ(arg any) Predicate {
+	return Predicate{
+		left:  m,
+		op:    opNEQ,
+		right: valueOf(arg),
+	}
+}
+
+func (m MathExpr) LT(arg any) Predicate {
+	return Predicate{
+		left:  m,
+		op:    opLT,
+		right: valueOf(arg),
+	}
+}
+
+func (m MathExpr) LTEQ(arg any) Predicate {
+	return Predicate{
+		left:  m,
+		op:    opLTEQ,
+		right: valueOf(arg),
+	}
+}
+
+func (m MathExpr) GT(arg any) Predicate {
+	return Predicate{
+		left:  m,
+		op:    opGT,
+		right: valueOf(arg),
+	}
+}
+
+func (m MathExpr) GTEQ(arg any) Predicate {
+	return Predicate{
+		left:  m,
+		op:    opGTEQ,
+		right: valueOf(arg),
+	}
+}
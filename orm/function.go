@@ -0,0 +1,139 @@
+package orm
+
+// FuncExpr 代表 SQL 函数调用
+// 例如 Lower(C("FirstName")) 会被构造为 LOWER(`first_name`)
+// 参数如果不是 Expression，那么会作为查询参数
+type FuncExpr struct {
+	fn   string
+	args []Expression
+	// 类似于 CURRENT_TIMESTAMP 这种不需要括号的
+	noParens bool
+	alias    string
+}
+
+func (f FuncExpr) expr()       {}
+func (f FuncExpr) selectable() {}
+
+func newFuncExpr(fn string, args ...any) FuncExpr {
+	exprs := make([]Expression, 0, len(args))
+	for _, arg := range args {
+		exprs = append(exprs, valueOf(arg))
+	}
+	return FuncExpr{
+		fn:   fn,
+		args: exprs,
+	}
+}
+
+func Lower(arg any) FuncExpr {
+	return newFuncExpr("LOWER", arg)
+}
+
+func Upper(arg any) FuncExpr {
+	return newFuncExpr("UPPER", arg)
+}
+
+// Coalesce 返回第一个不为 NULL 的参数
+// 例如 Coalesce(C("LastName"), "")
+func Coalesce(args ...any) FuncExpr {
+	return newFuncExpr("COALESCE", args...)
+}
+
+const fnConcat = "CONCAT"
+
+// Concat 例如 Concat(C("FirstName"), " ", C("LastName"))
+// 由方言决定怎么拼接，MySQL 是 CONCAT(...)，其余的是标准的 ||
+func Concat(args ...any) FuncExpr {
+	return newFuncExpr(fnConcat, args...)
+}
+
+// Now 当前时间
+// MySQL 和 SQLite 都支持 CURRENT_TIMESTAMP，而 SQLite 不支持 NOW()
+func Now() FuncExpr {
+	return FuncExpr{
+		fn:       "CURRENT_TIMESTAMP",
+		noParens: true,
+	}
+}
+
+// As 在 SELECT 部分使用的别名
+func (f FuncExpr) As(alias string) FuncExpr {
+	return FuncExpr{
+		fn:       f.fn,
+		args:     f.args,
+		noParens: f.noParens,
+		alias:    alias,
+	}
+}
+
+func (f FuncExpr) Eq(arg any) Predicate {
+	return Predicate{
+		left:  f,
+		op:    opEq,
+		right: valueOf(arg),
+	}
+}
+
+func (f FuncExpr) NEQ(arg any) Predicate {
+	return Predicate{
+		left:  f,
+		op:    opNEQ,
+		right: valueOf(arg),
+	}
+}
+
+func (f FuncExpr) LT(arg any) Predicate {
+	return Predicate{
+		left:  f,
+		op:    opLT,
+		right: valueOf(arg),
+	}
+}
+
+func (f FuncExpr) LTEQ(arg any) Predicate {
+	return Predicate{
+		left:  f,
+		op:    opLTEQ,
+		right: valueOf(arg),
+	}
+}
+
+func (f FuncExpr) GT(arg any) Predicate {
+	return Predicate{
+		left:  f,
+		op:    opGT,
+		right: valueOf(arg),
+	}
+}
+
+func (f FuncExpr) GTEQ(arg any) Predicate {
+	return Predicate{
+		left:  f,
+		op:    opGTEQ,
+		right: valueOf(arg),
+	}
+}
+
+func (f FuncExpr) Like(pattern string) Predicate {
+	return Predicate{
+		left:  f,
+		op:    opLike,
+		right: valueOf(pattern),
+	}
+}
+
+func (f FuncExpr) Add(val any) MathExpr {
+	return MathExpr{
+		left:  f,
+		op:    opAdd,
+		right: valueOf(val),
+	}
+}
+
+func (f FuncExpr) Sub(val any) MathExpr {
+	return MathExpr{
+		left:  f,
+		op:    opSub,
+		right: valueOf(val),
+	}
+}
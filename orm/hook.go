@@ -0,0 +1,53 @@
+package orm
+
+import "context"
+
+// BeforeInsert 实体实现了这个接口，Inserter 执行之前会调用，返回 error 会中断插入
+type BeforeInsert interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// AfterInsert 实体实现了这个接口，Inserter 执行成功之后会调用
+type AfterInsert interface {
+	AfterInsert(ctx context.Context) error
+}
+
+// BeforeUpdate 实体实现了这个接口，Updater 执行之前会调用，返回 error 会中断更新
+// 只有通过 Update 传入实体的时候才会调用
+type BeforeUpdate interface {
+	BeforeUpdate(ctx context.Context) error
+}
+
+// AfterFind 实体实现了这个接口，查询出来的数据 SetColumns 之后会调用
+type AfterFind interface {
+	AfterFind(ctx context.Context) error
+}
+
+func beforeInsert[T any](ctx context.Context, vals []*T) error {
+	for _, val := range vals {
+		if h, ok := any(val).(BeforeInsert); ok {
+			if err := h.BeforeInsert(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func afterInsert[T any](ctx context.Context, vals []*T) error {
+	for _, val := range vals {
+		if h, ok := any(val).(AfterInsert); ok {
+			if err := h.AfterInsert(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func afterFind(ctx context.Context, val any) error {
+	if h, ok := val.(AfterFind); ok {
+		return h.AfterFind(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.UnixMilli(1700000000000)
+
+type TimeModel struct {
+	Id        int64 `orm:"pk"`
+	Name      string
+	CreatedAt time.Time `orm:"auto_create_time"`
+	// 毫秒时间戳
+	UpdatedAt int64 `orm:"autoUpdateTime"`
+}
+
+type HookModel struct {
+	Id     int64 `orm:"pk"`
+	Name   string
+	events []string `orm:"-"`
+	err    error    `orm:"-"`
+}
+
+func (h *HookModel) BeforeInsert(ctx context.Context) error {
+	h.events = append(h.events, "BeforeInsert")
+	return h.err
+}
+
+func (h *HookModel) AfterInsert(ctx context.Context) error {
+	h.events = append(h.events, "AfterInsert")
+	return nil
+}
+
+func (h *HookModel) BeforeUpdate(ctx context.Context) error {
+	h.events = append(h.events, "BeforeUpdate")
+	return h.err
+}
+
+func (h *HookModel) AfterFind(ctx context.Context) error {
+	if h.Name == "" {
+		return errors.New("name 不能为空")
+	}
+	h.events = append(h.events, "AfterFind")
+	return nil
+}
+
+func TestHook(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// 插入
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `hook_model`(`id`,`name`) VALUES (?,?);")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	hm := &HookModel{Id: 1, Name: "Tom"}
+	require.NoError(t, NewInserter[HookModel](db).Values(hm).Exec(ctx).Err())
+	assert.Equal(t, []string{"BeforeInsert", "AfterInsert"}, hm.events)
+
+	// BeforeInsert 返回 error 中断插入
+	hm = &HookModel{Id: 2, err: errors.New("hook error")}
+	err = NewInserter[HookModel](db).Values(hm).Exec(ctx).Err()
+	assert.Equal(t, errors.New("hook error"), err)
+	assert.Equal(t, []string{"BeforeInsert"}, hm.events)
+
+	// 更新
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `hook_model` SET `name`=? WHERE `id` = ?;")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	hm = &HookModel{Id: 1, Name: "Jerry"}
+	require.NoError(t, NewUpdater[HookModel](db).Update(hm).Exec(ctx).Err())
+	assert.Equal(t, []string{"BeforeUpdate"}, hm.events)
+
+	hm = &HookModel{Id: 1, err: errors.New("hook error")}
+	err = NewUpdater[HookModel](db).Update(hm).Exec(ctx).Err()
+	assert.Equal(t, errors.New("hook error"), err)
+
+	// 查询
+	mock.ExpectQuery("SELECT .*").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+	res, err := NewSelector[HookModel](db).Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"AfterFind"}, res.events)
+
+	mock.ExpectQuery("SELECT .*").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom").AddRow(2, ""))
+	_, err = NewSelector[HookModel](db).GetMulti(ctx)
+	assert.Equal(t, errors.New("name 不能为空"), err)
+
+	mock.ExpectQuery("SELECT .*").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, ""))
+	for _, err = range NewSelector[HookModel](db).Iter(ctx) {
+		assert.Equal(t, errors.New("name 不能为空"), err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,7 +3,9 @@ package orm
 import (
 	"context"
 	"database/sql"
+	"reflect"
 	"scaffolding-go/orm/internal/errs"
+	"scaffolding-go/orm/internal/valuer"
 	"scaffolding-go/orm/model"
 )
 
@@ -17,6 +19,16 @@ type Upsert struct {
 	conflictColumns []string
 }
 
+// ConflictColumns 冲突的字段，没有指定的时候为空
+func (u *Upsert) ConflictColumns() []string {
+	return u.conflictColumns
+}
+
+// Assigns 冲突的时候要更新的列
+func (u *Upsert) Assigns() []Assignable {
+	return u.assigns
+}
+
 func (o *UpsertBuilder[T]) ConflictColumns(cols ...string) *UpsertBuilder[T] {
 	o.conflictColumns = cols
 	return o
@@ -41,6 +53,12 @@ type Inserter[T any] struct {
 	columns []string
 	//onDuplicateKey []Assignable
 	onDuplicateKey *Upsert
+	// RETURNING 的字段
+	returning []string
+	// 是否回填自增主键
+	backfill bool
+	// ExecBatch 每一批的行数
+	batchSize int
 }
 
 func NewInserter[T any](sess Session) *Inserter[T] {
@@ -76,10 +94,35 @@ func (i *Inserter[T]) Columns(cols ...string) *Inserter[T] {
 	return i
 }
 
+// Returning 指定 RETURNING 的字段，只有支持 RETURNING 的方言才能使用
+// Exec 的时候，返回的列会按照顺序写回 Values 传入的实体
+func (i *Inserter[T]) Returning(cols ...string) *Inserter[T] {
+	i.returning = cols
+	return i
+}
+
+// BackfillPK 插入之后把数据库生成的自增主键写回 Values 传入的实体
+// 支持 RETURNING 的方言使用 RETURNING，其余的使用 LastInsertId 推算
+// 没有指定列的时候，自增列不会出现在 INSERT 语句里面
+//
+// MySQL 推算要求 auto_increment_increment = 1，并且没有使用 upsert
+func (i *Inserter[T]) BackfillPK() *Inserter[T] {
+	i.backfill = true
+	return i
+}
+
+// BatchSize 指定 ExecBatch 每一批最多插入多少行
+// 没有指定的时候是 1000 行，并且占位符不超过 maxPlaceholders
+func (i *Inserter[T]) BatchSize(n int) *Inserter[T] {
+	i.batchSize = n
+	return i
+}
+
 func (i *Inserter[T]) Build() (*Query, error) {
 	if len(i.values) == 0 {
 		return nil, errs.ErrInsertZeroRow
 	}
+	i.reset()
 	i.sb.WriteString("INSERT INTO ")
 	if i.model == nil {
 		m, err := i.r.Get(i.values[0])
@@ -94,6 +137,21 @@ func (i *Inserter[T]) Build() (*Query, error) {
 	// 我们要构造列的名字，类似 `test_model`(col1,col2)
 	i.sb.WriteByte('(')
 	fields := i.model.Fields
+	var auto *model.Field
+	if i.backfill {
+		var err error
+		auto, err = i.autoIncrementField()
+		if err != nil {
+			return nil, err
+		}
+		// 让数据库生成自增列
+		fields = make([]*model.Field, 0, len(i.model.Fields)-1)
+		for _, fd := range i.model.Fields {
+			if fd != auto {
+				fields = append(fields, fd)
+			}
+		}
+	}
 	// 用户指定了列
 	if len(i.columns) > 0 {
 		fields = make([]*model.Field, 0, len(i.columns))
@@ -120,6 +178,8 @@ func (i *Inserter[T]) Build() (*Query, error) {
 
 	// 预估参数数量是：我有多少行乘以我有多少个字段
 	i.args = make([]any, 0, len(i.values)*len(fields))
+	// 同一个语句里面的时间保持一致
+	now := i.now()
 	for j, v := range i.values {
 		if j > 0 {
 			i.sb.WriteByte(',')
@@ -130,13 +190,16 @@ func (i *Inserter[T]) Build() (*Query, error) {
 			if idx > 0 {
 				i.sb.WriteByte(',')
 			}
-			i.sb.WriteString("?")
 			// 把参数读出来
 			arg, err := val.Field(field.GoName)
 			if err != nil {
 				return nil, err
 			}
-			i.addArg(arg)
+			if (field.AutoCreateTime || field.AutoUpdateTime) && isZero(arg) {
+				arg = timeValue(field, now)
+				i.addAutoValue(v, field.GoName, arg)
+			}
+			i.writeArg(arg)
 		}
 		i.sb.WriteByte(')')
 	}
@@ -146,10 +209,44 @@ func (i *Inserter[T]) Build() (*Query, error) {
 			return nil, err
 		}
 	}
+	returning := i.returning
+	if len(returning) == 0 && auto != nil && i.dialect.supportReturning() {
+		returning = []string{auto.GoName}
+	}
+	if len(returning) > 0 {
+		if err := i.buildReturning(returning); err != nil {
+			return nil, err
+		}
+	}
 	i.sb.WriteByte(';')
 	return &Query{SQL: i.sb.String(), Args: i.args}, nil
 }
 
+func (i *Inserter[T]) autoIncrementField() (*model.Field, error) {
+	for _, fd := range i.model.Fields {
+		if fd.AutoIncrement {
+			return fd, nil
+		}
+	}
+	return nil, errs.ErrNoAutoIncrementField
+}
+
+func (i *Inserter[T]) buildReturning(cols []string) error {
+	if !i.dialect.supportReturning() {
+		return errs.ErrUnsupportedReturning
+	}
+	i.sb.WriteString(" RETURNING ")
+	for idx, col := range cols {
+		if idx > 0 {
+			i.sb.WriteByte(',')
+		}
+		if err := i.buildColumn(Column{name: col}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (i *Inserter[T]) Exec(ctx context.Context) Result {
 	var err error
 	i.model, err = i.r.Get(new(T))
@@ -158,19 +255,159 @@ func (i *Inserter[T]) Exec(ctx context.Context) Result {
 			err: err,
 		}
 	}
-	res := exec(ctx, i.sess, i.core, &QueryContext{
+	if err = beforeInsert(ctx, i.values); err != nil {
+		return Result{
+			err: err,
+		}
+	}
+	var res sql.Result
+	if i.model.Sharding != nil && len(i.values) > 0 {
+		res, err = i.shardingExec(ctx)
+	} else {
+		res, err = i.exec(ctx)
+	}
+	if err == nil {
+		err = afterInsert(ctx, i.values)
+	}
+	return Result{
+		err: err,
+		res: res,
+	}
+}
+
+// ExecBatch 把 Values 分成多批插入，避免一条语句的占位符或者长度超出数据库的限制
+// atomic 为 true 的时候所有批次在同一个事务里面执行，ctx 里面已经有事务或者 session 本身是 Tx 的话就加入
+// 否则每一批单独执行，出错的时候已经执行的批次不会回滚
+// 每一批都和 Exec 一样处理 OnDuplicateKey、Returning、BackfillPK 以及钩子
+// RowsAffected 是所有批次的总和
+func (i *Inserter[T]) ExecBatch(ctx context.Context, atomic bool) Result {
+	var err error
+	i.model, err = i.r.Get(new(T))
+	if err != nil {
+		return Result{
+			err: err,
+		}
+	}
+	if len(i.values) == 0 {
+		return Result{
+			err: errs.ErrInsertZeroRow,
+		}
+	}
+	if !atomic {
+		res, err := i.execBatch(ctx, i.sess)
+		return Result{
+			err: err,
+			res: res,
+		}
+	}
+	var res sql.Result
+	switch sess := i.sess.(type) {
+	case *DB:
+		err = sess.DoTx(ctx, func(ctx context.Context, tx *Tx) error {
+			var err error
+			res, err = i.execBatch(ctx, tx)
+			return err
+		}, nil)
+	case *Tx:
+		// 已经在事务里面了，提交和回滚由开启事务的那一方负责
+		res, err = i.execBatch(ctx, sess)
+	default:
+		err = errs.NewErrUnsupportedAtomicBatch(sess)
+	}
+	return Result{
+		err: err,
+		res: res,
+	}
+}
+
+// maxPlaceholders 是一条语句最多的占位符数量
+// MySQL 和 PostgreSQL 是 65535，SQLite 默认是 32766，这里取最小的
+const maxPlaceholders = 32766
+
+const defaultBatchSize = 1000
+
+func (i *Inserter[T]) execBatch(ctx context.Context, sess Session) (sql.Result, error) {
+	size := i.batchSize
+	if size <= 0 {
+		cols := len(i.columns)
+		if cols == 0 {
+			cols = len(i.model.Fields)
+		}
+		size = max(min(defaultBatchSize, maxPlaceholders/max(cols, 1)), 1)
+	}
+	res := make(multiResult, 0, (len(i.values)+size-1)/size)
+	for start := 0; start < len(i.values); start += size {
+		r := i.batch(sess, i.values[start:min(start+size, len(i.values))]).Exec(ctx)
+		if r.err != nil {
+			return res, r.err
+		}
+		res = append(res, r.res)
+	}
+	return res, nil
+}
+
+// batch 用同样的配置构造插入 vals 的 Inserter
+func (i *Inserter[T]) batch(sess Session, vals []*T) *Inserter[T] {
+	sub := NewInserter[T](sess)
+	sub.model = i.model
+	sub.values = vals
+	sub.columns = i.columns
+	sub.onDuplicateKey = i.onDuplicateKey
+	sub.returning = i.returning
+	sub.backfill = i.backfill
+	return sub
+}
+
+// exec 执行插入，需要的话回填自增主键
+func (i *Inserter[T]) exec(ctx context.Context) (sql.Result, error) {
+	qc := &QueryContext{
 		Type:    "INSERT",
 		Builder: i,
 		Model:   i.model,
-	})
+	}
+	var res *QueryResult
+	if len(i.returning) > 0 || (i.backfill && i.dialect.supportReturning()) {
+		res = execReturning[T](ctx, i.sess, i.core, qc, i.values)
+	} else {
+		res = exec(ctx, i.sess, i.core, qc)
+	}
 	var sqlRes sql.Result
 	if res.Result != nil {
 		sqlRes = res.Result.(sql.Result)
 	}
-	return Result{
-		err: res.Err,
-		res: sqlRes,
+	if res.Err != nil {
+		return sqlRes, res.Err
+	}
+	if err := i.writeAutoValues(); err != nil {
+		return sqlRes, err
+	}
+	if i.backfill && !i.dialect.supportReturning() {
+		return sqlRes, i.backfillLastInsertId(sqlRes)
+	}
+	return sqlRes, nil
+}
+
+// backfillLastInsertId 根据 LastInsertId 推算每一行的自增主键
+func (i *Inserter[T]) backfillLastInsertId(res sql.Result) error {
+	fd, err := i.autoIncrementField()
+	if err != nil {
+		return err
+	}
+	lastId, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	id := i.dialect.firstInsertId(lastId, len(i.values))
+	for _, val := range i.values {
+		fdVal, _ := valuer.FieldByName(reflect.ValueOf(val).Elem(), fd.GoName, true)
+		if fdVal.CanInt() {
+			fdVal.SetInt(id)
+		} else {
+			fdVal.SetUint(uint64(id))
+		}
+		id++
 	}
+	return nil
 }
 
 //func (i *Inserter[T]) execHandler(ctx context.Context, qc *QueryContext) *QueryResult {
@@ -5,8 +5,10 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
+	"regexp"
 	"scaffolding-go/orm/internal/errs"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -68,6 +70,20 @@ func TestInsert_SQLite_upsert(t *testing.T) {
 					int64(13), "Bob", int8(10), &sql.NullString{String: "Allice", Valid: true}},
 			},
 		},
+		{
+			name: "upsert-update expression",
+			i: NewInserter[TestModel](db).Values(&TestModel{
+				Id:        12,
+				FirstName: "Tom",
+				Age:       18,
+			}).Columns("Id", "FirstName", "Age").OnDuplicateKey().ConflictColumns("Id").
+				Update(Assign("Age", C("Age").Add(1)), Assign("FirstName", Lower(C("FirstName")))),
+			wantQuery: &Query{
+				SQL: "INSERT INTO `test_model`(`id`,`first_name`,`age`) VALUES (?,?,?)" +
+					" ON CONFLICT(`id`) DO UPDATE SET `age`=`age` + ?,`first_name`=LOWER(`first_name`);",
+				Args: []any{int64(12), "Tom", int8(18), 1},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -83,6 +99,15 @@ func TestInsert_SQLite_upsert(t *testing.T) {
 
 func TestInserter_Build(t *testing.T) {
 	db := memoryDB(t)
+	timeDB := memoryDB(t)
+	timeDB.now = func() time.Time {
+		return testNow
+	}
+	type IgnoreModel struct {
+		Id    int64
+		Name  string
+		Cache string `orm:"-"`
+	}
 	testCases := []struct {
 		name string
 		i    QueryBuilder
@@ -157,6 +182,20 @@ func TestInserter_Build(t *testing.T) {
 				Args: []any{int64(12), "Tom", int64(13), "Bob"},
 			},
 		},
+		{
+			// 忽略的字段不会插入
+			name: "ignored field",
+			i:    NewInserter[IgnoreModel](db).Values(&IgnoreModel{Id: 12, Name: "Tom", Cache: "cache"}),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO `ignore_model`(`id`,`name`) VALUES (?,?);",
+				Args: []any{int64(12), "Tom"},
+			},
+		},
+		{
+			name:    "ignored column",
+			i:       NewInserter[IgnoreModel](db).Columns("Cache").Values(&IgnoreModel{}),
+			wantErr: errs.NewErrUnknownField("Cache"),
+		},
 		{
 			name: "upsert-update value",
 			i: NewInserter[TestModel](db).Values(&TestModel{
@@ -204,6 +243,30 @@ func TestInserter_Build(t *testing.T) {
 					int64(13), "Bob", int8(10), &sql.NullString{String: "Allice", Valid: true}},
 			},
 		},
+		{
+			name: "upsert-update expression",
+			i: NewInserter[TestModel](db).Values(&TestModel{
+				Id:        12,
+				FirstName: "Tom",
+				Age:       18,
+			}).Columns("Id", "FirstName", "Age").OnDuplicateKey().
+				Update(Assign("Age", C("Age").Add(1)), Assign("FirstName", Concat(C("FirstName"), "-new"))),
+			wantQuery: &Query{
+				SQL: "INSERT INTO `test_model`(`id`,`first_name`,`age`) VALUES (?,?,?) " +
+					"ON DUPLICATE KEY UPDATE `age`=`age` + ?,`first_name`=CONCAT(`first_name`,?);",
+				Args: []any{int64(12), "Tom", int8(18), 1, "-new"},
+			},
+		},
+		{
+			name: "auto time",
+			i: NewInserter[TimeModel](timeDB).Values(&TimeModel{Id: 1},
+				&TimeModel{Id: 2, CreatedAt: time.UnixMilli(100), UpdatedAt: 200}),
+			wantQuery: &Query{
+				SQL: "INSERT INTO `time_model`(`id`,`name`,`created_at`,`updated_at`) VALUES (?,?,?,?),(?,?,?,?);",
+				Args: []any{int64(1), "", testNow, testNow.UnixMilli(),
+					int64(2), "", time.UnixMilli(100), int64(200)},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -271,4 +334,290 @@ func TestInserter_Exec(t *testing.T) {
 			assert.Equal(t, tc.affected, affected)
 		})
 	}
+
+	// 自动生成的时间执行成功之后写回实体
+	db.now = func() time.Time {
+		return testNow
+	}
+	mock.ExpectExec("INSERT INTO .*").WillReturnError(errors.New("db error"))
+	tm := &TimeModel{Id: 1}
+	require.Error(t, NewInserter[TimeModel](db).Values(tm).Exec(context.Background()).Err())
+	assert.Equal(t, &TimeModel{Id: 1}, tm)
+	mock.ExpectExec("INSERT INTO .*").WillReturnResult(driver.RowsAffected(2))
+	tms := []*TimeModel{{Id: 1}, {Id: 2, CreatedAt: time.UnixMilli(100)}}
+	require.NoError(t, NewInserter[TimeModel](db).Values(tms...).Exec(context.Background()).Err())
+	assert.Equal(t, []*TimeModel{
+		{Id: 1, CreatedAt: testNow, UpdatedAt: testNow.UnixMilli()},
+		{Id: 2, CreatedAt: time.UnixMilli(100), UpdatedAt: testNow.UnixMilli()},
+	}, tms)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInserter_ExecBatch(t *testing.T) {
+	dbErr := errors.New("db error")
+	values := func(n int) []*TestModel {
+		res := make([]*TestModel, 0, n)
+		for i := 0; i < n; i++ {
+			res = append(res, &TestModel{Id: int64(i + 1)})
+		}
+		return res
+	}
+	twoRows := regexp.QuoteMeta("INSERT INTO `test_model`(`id`,`first_name`,`age`,`last_name`) " +
+		"VALUES (?,?,?,?),(?,?,?,?);")
+	oneRow := regexp.QuoteMeta("INSERT INTO `test_model`(`id`,`first_name`,`age`,`last_name`) VALUES (?,?,?,?);")
+	testCases := []struct {
+		name     string
+		mock     func(mock sqlmock.Sqlmock)
+		i        func(db *DB) *Inserter[TestModel]
+		atomic   bool
+		wantErr  error
+		affected int64
+	}{
+		{
+			name: "independent",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(twoRows).WillReturnResult(sqlmock.NewResult(2, 2))
+				mock.ExpectExec(oneRow).WillReturnResult(sqlmock.NewResult(3, 1))
+			},
+			i: func(db *DB) *Inserter[TestModel] {
+				return NewInserter[TestModel](db).Values(values(3)...).BatchSize(2)
+			},
+			affected: 3,
+		},
+		{
+			name: "independent error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(twoRows).WillReturnResult(sqlmock.NewResult(2, 2))
+				mock.ExpectExec(twoRows).WillReturnError(dbErr)
+			},
+			i: func(db *DB) *Inserter[TestModel] {
+				return NewInserter[TestModel](db).Values(values(6)...).BatchSize(2)
+			},
+			wantErr: dbErr,
+		},
+		{
+			name: "atomic",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(twoRows).WillReturnResult(sqlmock.NewResult(2, 2))
+				mock.ExpectExec(oneRow).WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectCommit()
+			},
+			i: func(db *DB) *Inserter[TestModel] {
+				return NewInserter[TestModel](db).Values(values(3)...).BatchSize(2)
+			},
+			atomic:   true,
+			affected: 3,
+		},
+		{
+			name: "atomic rollback",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(twoRows).WillReturnResult(sqlmock.NewResult(2, 2))
+				mock.ExpectExec(oneRow).WillReturnError(dbErr)
+				mock.ExpectRollback()
+			},
+			i: func(db *DB) *Inserter[TestModel] {
+				return NewInserter[TestModel](db).Values(values(3)...).BatchSize(2)
+			},
+			atomic:  true,
+			wantErr: dbErr,
+		},
+		{
+			name: "atomic rollback earlier batches",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(twoRows).WillReturnResult(sqlmock.NewResult(2, 2))
+				mock.ExpectExec(twoRows).WillReturnResult(sqlmock.NewResult(4, 2))
+				mock.ExpectExec(oneRow).WillReturnError(dbErr)
+				mock.ExpectRollback()
+			},
+			i: func(db *DB) *Inserter[TestModel] {
+				return NewInserter[TestModel](db).Values(values(5)...).BatchSize(2)
+			},
+			atomic:  true,
+			wantErr: dbErr,
+		},
+		{
+			name: "upsert",
+			mock: func(mock sqlmock.Sqlmock) {
+				for i := 1; i <= 2; i++ {
+					mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_model`(`id`,`first_name`,`age`,`last_name`) "+
+						"VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE `age`=?;")).
+						WithArgs(int64(i), "", int8(0), nil, 19).
+						WillReturnResult(sqlmock.NewResult(0, 2))
+				}
+			},
+			i: func(db *DB) *Inserter[TestModel] {
+				return NewInserter[TestModel](db).Values(values(2)...).BatchSize(1).
+					OnDuplicateKey().Update(Assign("Age", 19))
+			},
+			affected: 4,
+		},
+		{
+			name: "default batch size",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO .*").WillReturnResult(sqlmock.NewResult(0, 1000))
+				mock.ExpectExec(oneRow).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			i: func(db *DB) *Inserter[TestModel] {
+				return NewInserter[TestModel](db).Values(values(1001)...)
+			},
+			affected: 1001,
+		},
+		{
+			name: "no values",
+			mock: func(mock sqlmock.Sqlmock) {},
+			i: func(db *DB) *Inserter[TestModel] {
+				return NewInserter[TestModel](db)
+			},
+			wantErr: errs.ErrInsertZeroRow,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			db, err := OpenDB(mockDB)
+			require.NoError(t, err)
+			tc.mock(mock)
+			res := tc.i(db).ExecBatch(context.Background(), tc.atomic)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			affected, err := res.RowsAffected()
+			// 事务会包装业务的错误
+			assert.ErrorIs(t, err, tc.wantErr)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.affected, affected)
+		})
+	}
+}
+
+func TestInserter_ExecBatchInTx(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	dbErr := errors.New("db error")
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO .*").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO .*").WillReturnError(dbErr)
+	mock.ExpectRollback()
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	// 加入 tx，不会另外开启事务
+	res := NewInserter[TestModel](tx).
+		Values(&TestModel{Id: 1}, &TestModel{Id: 2}).BatchSize(1).
+		ExecBatch(context.Background(), true)
+	assert.ErrorIs(t, res.Err(), dbErr)
+	require.NoError(t, tx.Rollback())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInserter_BackfillPK(t *testing.T) {
+	type AutoModel struct {
+		Id        int64 `orm:"pk=true,auto=true"`
+		FirstName string
+	}
+	type UintAutoModel struct {
+		Id        uint32 `orm:"pk=true,auto=true"`
+		FirstName string
+	}
+	testCases := []struct {
+		name    string
+		dialect Dialect
+		mock    func(mock sqlmock.Sqlmock)
+		exec    func(db *DB) (Result, any)
+		wantVal any
+		wantErr error
+	}{
+		{
+			name:    "mysql",
+			dialect: DialectMySQL,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `auto_model`(`first_name`) VALUES (?),(?);")).
+					WithArgs("Tom", "Bob").
+					WillReturnResult(sqlmock.NewResult(10, 2))
+			},
+			exec: func(db *DB) (Result, any) {
+				vals := []*AutoModel{{FirstName: "Tom"}, {FirstName: "Bob"}}
+				return NewInserter[AutoModel](db).Values(vals...).BackfillPK().
+					Exec(context.Background()), vals
+			},
+			wantVal: []*AutoModel{{Id: 10, FirstName: "Tom"}, {Id: 11, FirstName: "Bob"}},
+		},
+		{
+			name:    "sqlite",
+			dialect: DialectSQLite,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO .*").
+					WillReturnResult(sqlmock.NewResult(11, 2))
+			},
+			exec: func(db *DB) (Result, any) {
+				vals := []*UintAutoModel{{FirstName: "Tom"}, {FirstName: "Bob"}}
+				return NewInserter[UintAutoModel](db).Values(vals...).BackfillPK().
+					Exec(context.Background()), vals
+			},
+			wantVal: []*UintAutoModel{{Id: 10, FirstName: "Tom"}, {Id: 11, FirstName: "Bob"}},
+		},
+		{
+			name:    "postgres",
+			dialect: DialectPostgreSQL,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "auto_model"("first_name") VALUES ($1),($2) RETURNING "id";`)).
+					WithArgs("Tom", "Bob").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10).AddRow(11))
+			},
+			exec: func(db *DB) (Result, any) {
+				vals := []*AutoModel{{FirstName: "Tom"}, {FirstName: "Bob"}}
+				return NewInserter[AutoModel](db).Values(vals...).BackfillPK().
+					Exec(context.Background()), vals
+			},
+			wantVal: []*AutoModel{{Id: 10, FirstName: "Tom"}, {Id: 11, FirstName: "Bob"}},
+		},
+		{
+			name:    "last insert id error",
+			dialect: DialectMySQL,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO .*").
+					WillReturnResult(sqlmock.NewErrorResult(errors.New("no id")))
+			},
+			exec: func(db *DB) (Result, any) {
+				vals := []*AutoModel{{FirstName: "Tom"}}
+				return NewInserter[AutoModel](db).Values(vals...).BackfillPK().
+					Exec(context.Background()), vals
+			},
+			wantErr: errors.New("no id"),
+		},
+		{
+			name:    "no auto increment field",
+			dialect: DialectMySQL,
+			mock:    func(mock sqlmock.Sqlmock) {},
+			exec: func(db *DB) (Result, any) {
+				vals := []*TestModel{{FirstName: "Tom"}}
+				return NewInserter[TestModel](db).Values(vals...).BackfillPK().
+					Exec(context.Background()), vals
+			},
+			wantErr: errs.ErrNoAutoIncrementField,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			db, err := OpenDB(mockDB, DBWithDialect(tc.dialect))
+			require.NoError(t, err)
+			tc.mock(mock)
+			res, vals := tc.exec(db)
+			assert.Equal(t, tc.wantErr, res.Err())
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if res.Err() != nil {
+				return
+			}
+			assert.Equal(t, tc.wantVal, vals)
+		})
+	}
 }
@@ -16,6 +16,42 @@ var (
 
 	// ErrInsertZeroRow 代表插入 0 行
 	ErrInsertZeroRow = errors.New("orm: 插入 0 行")
+
+	// ErrNoUpdatedColumns 代表没有指定要更新的列
+	ErrNoUpdatedColumns = errors.New("orm: 没有指定要更新的列")
+
+	// ErrUpdateWithoutEntity 代表用 C("xx") 更新，但是没有调用 Update 传入实体
+	ErrUpdateWithoutEntity = errors.New("orm: 使用列更新必须先指定实体")
+
+	// ErrNoPrimaryKey 代表按照实体更新或者删除，但是模型没有主键，也没有指定 WHERE 条件
+	ErrNoPrimaryKey = errors.New("orm: 模型没有主键，必须指定 WHERE 条件")
+
+	// ErrUpsertWithoutConflictColumns 代表 upsert 没有指定冲突的列
+	ErrUpsertWithoutConflictColumns = errors.New("orm: upsert 必须指定冲突的列")
+
+	// ErrUnsupportedUpsert 代表方言不支持 upsert
+	ErrUnsupportedUpsert = errors.New("orm: 当前方言不支持 upsert")
+
+	// ErrUnsupportedReturning 代表方言不支持 RETURNING
+	ErrUnsupportedReturning = errors.New("orm: 当前方言不支持 RETURNING")
+
+	// ErrCompoundTooFewQueries 代表 UNION 之类的组合查询少于两个查询
+	ErrCompoundTooFewQueries = errors.New("orm: 组合查询至少需要两个查询")
+
+	// ErrNoAutoIncrementField 代表回填主键的时候，模型没有标记 auto=true 的字段
+	ErrNoAutoIncrementField = errors.New("orm: 模型没有自增列")
+
+	// ErrPreloadWithIter 代表 Iter 的时候调用了 Preload，Iter 不会把数据全部加载进内存，没办法批量加载关联关系
+	ErrPreloadWithIter = errors.New("orm: Iter 不支持 Preload")
+
+	// ErrTxNotAllowed 代表 PropagationNever 的时候已经有事务了
+	ErrTxNotAllowed = errors.New("orm: 已经在事务里面了，不允许使用事务")
+
+	// ErrOptimisticLockConflict 代表按照版本号更新的时候，数据已经被别人修改了
+	ErrOptimisticLockConflict = errors.New("orm: 乐观锁冲突，数据已经被修改")
+
+	// ErrMultiResultLastInsertId 代表分片或者分批执行了多条语句，没有唯一的 LastInsertId
+	ErrMultiResultLastInsertId = errors.New("orm: 执行了多条语句，没有 LastInsertId")
 )
 
 // NewErrUnknownField 返回代表未知字段的错误
@@ -24,6 +60,11 @@ func NewErrUnknownField(fd string) error {
 	return fmt.Errorf("orm: 未知字段 %s", fd)
 }
 
+// NewErrInvalidFieldValue 代表值的类型和字段的类型对不上，也没办法转换
+func NewErrInvalidFieldValue(fd string, val any) error {
+	return fmt.Errorf("orm: 字段 %s 不能设置为 %T 类型的值", fd, val)
+}
+
 func NewErrUnknownColumn(name string) error {
 	return fmt.Errorf("orm: 未知列 %s", name)
 }
@@ -61,3 +102,112 @@ func NewErrFailedToRollbackTx(bizErr error, rbErr error, panicked bool) error {
 func NewErrUnsupportedTable(table any) error {
 	return fmt.Errorf("orm: 不支持的TableReference类型 %v", table)
 }
+
+// NewErrUnsupportedQueryResult 一般意味着中间件替换了 QueryResult.Result
+func NewErrUnsupportedQueryResult(res any) error {
+	return fmt.Errorf("orm: 不支持的查询结果类型 %T", res)
+}
+
+// NewErrCompoundColumnCount 代表组合查询中第 idx 个查询的列数和第一个查询不一致
+func NewErrCompoundColumnCount(idx int, got int, want int) error {
+	return fmt.Errorf("orm: 组合查询第 %d 个查询有 %d 列，期望 %d 列", idx, got, want)
+}
+
+// NewErrCompoundColumnType 代表组合查询中第 idx 个查询的列类型和第一个查询不一致
+func NewErrCompoundColumnType(idx int, col string, got any, want any) error {
+	return fmt.Errorf("orm: 组合查询第 %d 个查询的列 %s 类型是 %v，期望 %v", idx, col, got, want)
+}
+
+// NewErrCompoundOrderedMember 代表组合查询中第 idx 个查询有 ORDER BY 或者 LIMIT，但是方言不支持用括号括起来
+func NewErrCompoundOrderedMember(idx int) error {
+	return fmt.Errorf("orm: 组合查询第 %d 个查询不能单独使用 ORDER BY 或者 LIMIT", idx)
+}
+
+// NewErrInvalidAutoIncrementType 代表自增列不是整数类型
+func NewErrInvalidAutoIncrementType(fd string, typ any) error {
+	return fmt.Errorf("orm: 自增列 %s 必须是整数，实际类型 %v", fd, typ)
+}
+
+// NewErrDuplicateField 代表模型里面有两个同名的字段，一般是嵌入结构体引起的
+func NewErrDuplicateField(fd string, col1 string, col2 string) error {
+	return fmt.Errorf("orm: 字段 %s 重复，对应的列是 %s 和 %s", fd, col1, col2)
+}
+
+// NewErrDuplicateColumn 代表模型里面有两个字段映射到了同一个列
+func NewErrDuplicateColumn(col string, fd1 string, fd2 string) error {
+	return fmt.Errorf("orm: 列 %s 重复，对应的字段是 %s 和 %s", col, fd1, fd2)
+}
+
+// NewErrUnsupportedColumnType 代表迁移的时候，方言不知道字段应该用什么列类型
+func NewErrUnsupportedColumnType(fd string, typ any) error {
+	return fmt.Errorf("orm: 字段 %s 的类型 %v 没有对应的列类型", fd, typ)
+}
+
+// NewErrInvalidSoftDeleteType 代表软删除的字段不能表示 NULL
+func NewErrInvalidSoftDeleteType(fd string, typ any) error {
+	return fmt.Errorf("orm: 软删除字段 %s 必须是 *time.Time、sql.NullTime、整数指针或者 sql.NullInt64，实际类型 %v", fd, typ)
+}
+
+// NewErrMultipleSoftDelete 代表模型有多个软删除字段
+func NewErrMultipleSoftDelete(fd1 string, fd2 string) error {
+	return fmt.Errorf("orm: 只能有一个软删除字段，%s 和 %s", fd1, fd2)
+}
+
+// NewErrInvalidAutoTimeType 代表自动填充时间的字段不是时间或者整数
+func NewErrInvalidAutoTimeType(fd string, typ any) error {
+	return fmt.Errorf("orm: 自动填充时间的字段 %s 必须是时间或者整数，实际类型 %v", fd, typ)
+}
+
+// NewErrInvalidVersionType 代表版本号字段不是整数
+func NewErrInvalidVersionType(fd string, typ any) error {
+	return fmt.Errorf("orm: 版本号字段 %s 必须是整数，实际类型 %v", fd, typ)
+}
+
+// NewErrMultipleVersion 代表模型有多个版本号字段
+func NewErrMultipleVersion(fd1 string, fd2 string) error {
+	return fmt.Errorf("orm: 只能有一个版本号字段，%s 和 %s", fd1, fd2)
+}
+
+func NewErrUnsupportedPropagation(propagation any) error {
+	return fmt.Errorf("orm: 不支持的事务传播行为 %v", propagation)
+}
+
+// NewErrInvalidRelationType 代表关联关系的字段类型不对
+// has_one 和 belongs_to 必须是结构体指针，has_many 必须是结构体或者结构体指针的切片
+func NewErrInvalidRelationType(fd string, typ any) error {
+	return fmt.Errorf("orm: 关联字段 %s 的类型不对，实际类型 %v", fd, typ)
+}
+
+func NewErrUnknownRelation(name string) error {
+	return fmt.Errorf("orm: 未知关联关系 %s", name)
+}
+
+// NewErrRelationWithoutReferences 代表关联关系没有指定 references，并且对应的模型不是单一主键
+func NewErrRelationWithoutReferences(name string) error {
+	return fmt.Errorf("orm: 关联关系 %s 无法确定外键引用的字段，请使用 references 指定", name)
+}
+
+// NewErrInvalidForeignKey 代表关联关系的外键字段不存在
+// belongs_to 的外键在模型自己身上，has_one 和 has_many 的在关联的结构体上
+func NewErrInvalidForeignKey(name string, fk string) error {
+	return fmt.Errorf("orm: 关联关系 %s 的外键字段 %s 不存在", name, fk)
+}
+
+// NewErrShardingUnsupported 代表命中了多个分片，但是没办法合并结果或者没办法执行
+func NewErrShardingUnsupported(feature string) error {
+	return fmt.Errorf("orm: 跨分片不支持 %s", feature)
+}
+
+func NewErrUnknownShardDB(name string) error {
+	return fmt.Errorf("orm: 未知的分片数据库 %s", name)
+}
+
+// NewErrInvalidShardingKey 代表插入的数据没办法根据分片键确定唯一的分片
+func NewErrInvalidShardingKey(key string, val any) error {
+	return fmt.Errorf("orm: 分片键 %s=%v 没有对应唯一的分片", key, val)
+}
+
+// NewErrUnsupportedAtomicBatch 代表 session 没办法保证分批插入的原子性
+func NewErrUnsupportedAtomicBatch(sess any) error {
+	return fmt.Errorf("orm: %T 不支持原子的分批插入", sess)
+}
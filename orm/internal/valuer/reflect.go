@@ -23,7 +23,47 @@ func NewReflectValue(model *model.Model, val any) Value {
 }
 
 func (r reflectValue) Field(name string) (any, error) {
-	return r.val.FieldByName(name).Interface(), nil
+	fd, ok := r.model.FieldMap[name]
+	if !ok {
+		return nil, errs.NewErrUnknownField(name)
+	}
+	val, ok := FieldByName(r.val, name, false)
+	if !ok {
+		// 嵌入的指针是 nil，返回零值
+		return reflect.Zero(fd.Typ).Interface(), nil
+	}
+	return val.Interface(), nil
+}
+
+func (r reflectValue) SetField(name string, val any) error {
+	fd, ok := r.model.FieldMap[name]
+	if !ok {
+		return errs.NewErrUnknownField(name)
+	}
+	fdVal, _ := FieldByName(r.val, name, true)
+	return setValue(fd, fdVal, val)
+}
+
+// FieldByName 和 reflect.Value.FieldByName 类似，但是不会因为嵌入的指针是 nil 而 panic
+// 嵌入的指针是 nil 的时候：alloc 为 true 就创建一个新的结构体，否则返回 false
+func FieldByName(val reflect.Value, name string, alloc bool) (reflect.Value, bool) {
+	sf, ok := val.Type().FieldByName(name)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	for i, idx := range sf.Index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				val.Set(reflect.New(val.Type().Elem()))
+			}
+			val = val.Elem()
+		}
+		val = val.Field(idx)
+	}
+	return val, true
 }
 
 func (r reflectValue) SetColumns(rows *sql.Rows) error {
@@ -68,7 +108,8 @@ func (r reflectValue) SetColumns(rows *sql.Rows) error {
 		if !ok {
 			return errs.NewErrUnknownColumn(c)
 		}
-		tpValue.FieldByName(fd.GoName).Set(valElems[i])
+		fdVal, _ := FieldByName(tpValue, fd.GoName, true)
+		fdVal.Set(valElems[i])
 	}
 	return err
 }
@@ -2,8 +2,10 @@ package valuer
 
 import (
 	"database/sql"
+	"scaffolding-go/orm/internal/errs"
 	"scaffolding-go/orm/model"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -79,6 +81,32 @@ func testSetColumns(t *testing.T, creator Creator) {
 				},
 			},
 		},
+		{
+			name:   "embedded",
+			entity: &EmbedModel{},
+			rows: func() *sqlmock.Rows {
+				rows := sqlmock.NewRows([]string{"id", "name", "created_at", "deleted_at"})
+				rows.AddRow("1", "Tom", "100", "200")
+				return rows
+			},
+			wantEntity: &EmbedModel{
+				BaseModel: BaseModel{Id: 1, CreatedAt: 100},
+				Name:      "Tom",
+				Deleted:   &Deleted{DeletedAt: 200},
+			},
+		},
+		{
+			name:   "embedded pointer not nil",
+			entity: &EmbedModel{Deleted: &Deleted{DeletedAt: 10}},
+			rows: func() *sqlmock.Rows {
+				rows := sqlmock.NewRows([]string{"deleted_at"})
+				rows.AddRow("200")
+				return rows
+			},
+			wantEntity: &EmbedModel{
+				Deleted: &Deleted{DeletedAt: 200},
+			},
+		},
 	}
 	r := model.NewRegistry()
 	mockDB, mock, err := sqlmock.New()
@@ -109,6 +137,164 @@ func testSetColumns(t *testing.T, creator Creator) {
 	}
 }
 
+func Test_reflectValue_Field(t *testing.T) {
+	testField(t, NewReflectValue)
+}
+
+func testField(t *testing.T, creator Creator) {
+	testCases := []struct {
+		name    string
+		entity  any
+		field   string
+		wantVal any
+		wantErr error
+	}{
+		{
+			name:    "field",
+			entity:  &EmbedModel{Name: "Tom"},
+			field:   "Name",
+			wantVal: "Tom",
+		},
+		{
+			name:    "embedded",
+			entity:  &EmbedModel{BaseModel: BaseModel{CreatedAt: 100}},
+			field:   "CreatedAt",
+			wantVal: int64(100),
+		},
+		{
+			name:    "embedded pointer",
+			entity:  &EmbedModel{Deleted: &Deleted{DeletedAt: 200}},
+			field:   "DeletedAt",
+			wantVal: int64(200),
+		},
+		{
+			name:    "nil embedded pointer",
+			entity:  &EmbedModel{},
+			field:   "DeletedAt",
+			wantVal: int64(0),
+		},
+		{
+			name:    "unknown field",
+			entity:  &EmbedModel{},
+			field:   "Invalid",
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+	r := model.NewRegistry()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := r.Get(tc.entity)
+			require.NoError(t, err)
+			val, err := creator(m, tc.entity).Field(tc.field)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantVal, val)
+		})
+	}
+}
+
+func Test_reflectValue_SetField(t *testing.T) {
+	testSetField(t, NewReflectValue)
+}
+
+func testSetField(t *testing.T, creator Creator) {
+	type TimeModel struct {
+		Id        int64
+		CreatedAt time.Time
+		UpdatedAt *time.Time
+		NullAt    sql.NullTime
+		Milli     int
+	}
+	now := time.UnixMilli(1700000000000)
+	testCases := []struct {
+		name       string
+		entity     any
+		field      string
+		val        any
+		wantEntity any
+		wantErr    error
+	}{
+		{
+			name:       "assignable",
+			entity:     &TimeModel{},
+			field:      "CreatedAt",
+			val:        now,
+			wantEntity: &TimeModel{CreatedAt: now},
+		},
+		{
+			name:       "pointer",
+			entity:     &TimeModel{},
+			field:      "UpdatedAt",
+			val:        now,
+			wantEntity: &TimeModel{UpdatedAt: &now},
+		},
+		{
+			name:       "scanner",
+			entity:     &TimeModel{},
+			field:      "NullAt",
+			val:        now,
+			wantEntity: &TimeModel{NullAt: sql.NullTime{Time: now, Valid: true}},
+		},
+		{
+			name:       "convert",
+			entity:     &TimeModel{},
+			field:      "Milli",
+			val:        now.UnixMilli(),
+			wantEntity: &TimeModel{Milli: int(now.UnixMilli())},
+		},
+		{
+			name:       "nil embedded pointer",
+			entity:     &EmbedModel{},
+			field:      "DeletedAt",
+			val:        int64(200),
+			wantEntity: &EmbedModel{Deleted: &Deleted{DeletedAt: 200}},
+		},
+		{
+			name:    "invalid value",
+			entity:  &TimeModel{},
+			field:   "CreatedAt",
+			val:     "now",
+			wantErr: errs.NewErrInvalidFieldValue("CreatedAt", "now"),
+		},
+		{
+			name:    "unknown field",
+			entity:  &TimeModel{},
+			field:   "Invalid",
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+	r := model.NewRegistry()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := r.Get(tc.entity)
+			require.NoError(t, err)
+			err = creator(m, tc.entity).SetField(tc.field, tc.val)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantEntity, tc.entity)
+		})
+	}
+}
+
+type BaseModel struct {
+	Id        int64
+	CreatedAt int64
+}
+
+type Deleted struct {
+	DeletedAt int64
+}
+
+type EmbedModel struct {
+	BaseModel
+	Name string
+	*Deleted
+}
+
 type TestModel struct {
 	Id        int64
 	FirstName string
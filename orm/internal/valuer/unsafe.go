@@ -29,9 +29,11 @@ func (r unsafeValue) Field(name string) (any, error) {
 	if !ok {
 		return nil, errs.NewErrUnknownField(name)
 	}
-	// 是不是要计算字段的地址?
-	// 起始地址 + 偏移量
-	fdAddress := unsafe.Pointer(uintptr(r.address) + fd.Offset)
+	fdAddress := r.fieldAddress(fd, false)
+	if fdAddress == nil {
+		// 嵌入的指针是 nil，返回零值
+		return reflect.Zero(fd.Typ).Interface(), nil
+	}
 	// 反射在特定的地址上，创建一个特定类型的实例
 	// 这里创建的实例是原本类型的指针类型
 	// 例如 fd.Type = int 那么val 就是 *int
@@ -39,6 +41,15 @@ func (r unsafeValue) Field(name string) (any, error) {
 	return val.Elem().Interface(), nil
 }
 
+func (r unsafeValue) SetField(name string, val any) error {
+	fd, ok := r.model.FieldMap[name]
+	if !ok {
+		return errs.NewErrUnknownField(name)
+	}
+	fdVal := reflect.NewAt(fd.Typ, r.fieldAddress(fd, true)).Elem()
+	return setValue(fd, fdVal, val)
+}
+
 func (r unsafeValue) SetColumns(rows *sql.Rows) error {
 	// 我怎么知道你 SELECT 出来了那些列
 	// 拿到了 SELECT 出来的列
@@ -56,9 +67,7 @@ func (r unsafeValue) SetColumns(rows *sql.Rows) error {
 		if !ok {
 			return errs.NewErrUnknownColumn(c)
 		}
-		// 是不是要计算字段的地址?
-		// 起始地址 + 偏移量
-		fdAddress := unsafe.Pointer(uintptr(r.address) + fd.Offset)
+		fdAddress := r.fieldAddress(fd, true)
 		// 反射在特定的地址上，创建一个特定类型的实例
 		// 这里创建的实例是原本类型的指针类型
 		// 例如 fd.Type = int 那么val 就是 *int
@@ -68,3 +77,22 @@ func (r unsafeValue) SetColumns(rows *sql.Rows) error {
 	err = rows.Scan(vals...)
 	return err
 }
+
+// fieldAddress 计算字段的地址
+// 字段在嵌入的指针里面时，需要逐层解引用，指针是 nil 的时候：
+// alloc 为 true 就创建一个新的结构体，否则返回 nil
+func (r unsafeValue) fieldAddress(fd *model.Field, alloc bool) unsafe.Pointer {
+	address := r.address
+	for _, ptr := range fd.EmbeddedPtrs {
+		ptrAddress := (*unsafe.Pointer)(unsafe.Pointer(uintptr(address) + ptr.Offset))
+		if *ptrAddress == nil {
+			if !alloc {
+				return nil
+			}
+			*ptrAddress = reflect.New(ptr.Typ).UnsafePointer()
+		}
+		address = *ptrAddress
+	}
+	// 起始地址 + 偏移量
+	return unsafe.Pointer(uintptr(address) + fd.Offset)
+}
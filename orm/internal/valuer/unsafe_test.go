@@ -7,3 +7,11 @@ import (
 func Test_unsafeValue_SetColumns(t *testing.T) {
 	testSetColumns(t, NewUnsafeValue)
 }
+
+func Test_unsafeValue_Field(t *testing.T) {
+	testField(t, NewUnsafeValue)
+}
+
+func Test_unsafeValue_SetField(t *testing.T) {
+	testSetField(t, NewUnsafeValue)
+}
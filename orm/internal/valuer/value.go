@@ -2,12 +2,51 @@ package valuer
 
 import (
 	"database/sql"
+	"reflect"
+	"scaffolding-go/orm/internal/errs"
 	"scaffolding-go/orm/model"
 )
 
 type Value interface {
 	Field(name string) (any, error)
 	SetColumns(rows *sql.Rows) error
+	// SetField 把 val 写入字段，例如回填自动生成的创建时间
+	// 类型不一致的时候会尝试转换，例如 time.Time 写入 sql.NullTime
+	SetField(name string, val any) error
 }
 
 type Creator func(model *model.Model, entity any) Value
+
+// setValue 把 val 写入 dst
+// 字段是指针的时候创建一个新的值，实现了 sql.Scanner 的交给 Scan，数字之间直接转换
+func setValue(fd *model.Field, dst reflect.Value, val any) error {
+	src := reflect.ValueOf(val)
+	if !src.IsValid() {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		elem := reflect.New(dst.Type().Elem())
+		if err := setValue(fd, elem.Elem(), val); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	}
+	if sc, ok := dst.Addr().Interface().(sql.Scanner); ok {
+		return sc.Scan(val)
+	}
+	if isNumber(src.Kind()) && isNumber(dst.Kind()) {
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+	return errs.NewErrInvalidFieldValue(fd.GoName, val)
+}
+
+func isNumber(kind reflect.Kind) bool {
+	return (kind >= reflect.Int && kind <= reflect.Uint64) || kind == reflect.Float32 || kind == reflect.Float64
+}
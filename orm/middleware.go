@@ -2,22 +2,38 @@ package orm
 
 import (
 	"context"
+	"reflect"
 	"scaffolding-go/orm/model"
 )
 
 type QueryContext struct {
 	// 查询类型，标记crud
+	// Selector 的流式查询（Iter）的类型是 ITER，原生查询不管怎么执行都是 RAW
+	// RawQueryOf 执行组合查询的时候和 Selector 一样
 	Type string
 
 	// 代表查询本身
 	Builder QueryBuilder
 
 	Model *model.Model
+
+	// ResultType 是 QueryResult.Result 的类型，例如 *T 或者 []*T
+	// 缓存之类的中间件需要据此反序列化，nil 代表不确定
+	ResultType reflect.Type
+
+	// FullTable 代表 UPDATE 或者 DELETE 没有用户指定的条件，会作用于整张表
+	// 软删除自动加上的 deleted_at IS NULL 不算用户的条件
+	FullTable bool
+
+	// Tx 语句所在的事务，不在事务里面的时候是 nil
+	// 事务里面的修改提交之后才对别人可见，需要的话用 Tx.OnCommit 延后处理
+	Tx *Tx
 }
 
 type QueryResult struct {
 	// Result 在不同的查询下类型是不同的
 	// Select --> []*T 或者 *T
+	// Iter --> *sql.Rows，由迭代器负责关闭
 	// 其他就是类型 Result
 	Result any
 
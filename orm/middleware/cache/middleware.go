@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"scaffolding-go/cache"
+	"scaffolding-go/orm"
+	"strconv"
+	"time"
+)
+
+// MiddlewareBuilder 缓存 SELECT 的结果
+// 缓存的 key 由表的版本号加上 SQL 和参数组成，INSERT、UPDATE、DELETE 会更新表的版本号，
+// 这样旧的缓存就再也不会被读到，等着过期就可以了
+// 注意：
+//  1. 只按照 Model.TableName 失效，JOIN、子查询以及组合查询涉及的其它表修改了是感知不到的
+//  2. RAW 类型的语句不会缓存，也不会让缓存失效
+//  3. 事务里面的查询不读也不写缓存，事务里面的修改在提交之后才让缓存失效
+//  4. 结果用 JSON 序列化，实体上 json:"-" 的字段不会被缓存
+type MiddlewareBuilder struct {
+	c          cache.Cache
+	expiration time.Duration
+	prefix     string
+}
+
+func NewMiddlewareBuilder(c cache.Cache) *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		c:          c,
+		expiration: time.Minute,
+		prefix:     "orm:",
+	}
+}
+
+// Expiration 缓存的过期时间，默认一分钟
+func (m *MiddlewareBuilder) Expiration(expiration time.Duration) *MiddlewareBuilder {
+	m.expiration = expiration
+	return m
+}
+
+// Prefix 所有 key 的前缀，默认是 orm:
+func (m *MiddlewareBuilder) Prefix(prefix string) *MiddlewareBuilder {
+	m.prefix = prefix
+	return m
+}
+
+func (m MiddlewareBuilder) Build() orm.Middleware {
+	return func(next orm.Handler) orm.Handler {
+		return func(ctx context.Context, qc *orm.QueryContext) *orm.QueryResult {
+			switch qc.Type {
+			case "SELECT":
+				// 事务里面可能读到自己还没有提交的修改
+				if qc.Tx != nil {
+					return next(ctx, qc)
+				}
+				return m.query(ctx, qc, next)
+			case "INSERT", "UPDATE", "DELETE":
+				res := next(ctx, qc)
+				// 执行失败也可能改了数据，例如超时，所以总是失效
+				if qc.Tx == nil {
+					m.invalidate(ctx, qc)
+					return res
+				}
+				// 提交之前别人看到的还是旧数据，提交之后再失效
+				// 回滚了就没有必要失效
+				ctx = context.WithoutCancel(ctx)
+				qc.Tx.OnCommit(func() {
+					m.invalidate(ctx, qc)
+				})
+				return res
+			default:
+				return next(ctx, qc)
+			}
+		}
+	}
+}
+
+func (m MiddlewareBuilder) query(ctx context.Context, qc *orm.QueryContext, next orm.Handler) *orm.QueryResult {
+	// 不知道结果的类型，没办法反序列化
+	if qc.ResultType == nil {
+		return next(ctx, qc)
+	}
+	key, err := m.key(ctx, qc)
+	if err != nil {
+		return next(ctx, qc)
+	}
+	if val, err := m.c.Get(ctx, key); err == nil {
+		if res, ok := decode(val, qc.ResultType); ok {
+			return &orm.QueryResult{
+				Result: res,
+			}
+		}
+	}
+	res := next(ctx, qc)
+	// ErrNoRows 之类的错误不缓存
+	if res.Err != nil {
+		return res
+	}
+	if data, err := json.Marshal(res.Result); err == nil {
+		_ = m.c.Set(ctx, key, data, m.expiration)
+	}
+	return res
+}
+
+// key 是 前缀 + 表名 + 表的版本号 + SQL 和参数的摘要
+func (m MiddlewareBuilder) key(ctx context.Context, qc *orm.QueryContext) (string, error) {
+	q, err := qc.Builder.Build()
+	if err != nil {
+		return "", err
+	}
+	version, err := m.version(ctx, qc)
+	if err != nil {
+		return "", err
+	}
+	args, err := json.Marshal(q.Args)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(q.SQL))
+	h.Write([]byte{0})
+	h.Write(args)
+	return m.prefix + qc.Model.TableName + ":" + version + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// version 表的版本号，没有的时候创建一个
+func (m MiddlewareBuilder) version(ctx context.Context, qc *orm.QueryContext) (string, error) {
+	val, err := m.c.Get(ctx, m.versionKey(qc))
+	if err == nil {
+		if version, ok := toBytes(val); ok {
+			return string(version), nil
+		}
+	}
+	version := newVersion()
+	if err = m.c.Set(ctx, m.versionKey(qc), version, 0); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// invalidate 更新表的版本号，旧的缓存就不会再被读到
+func (m MiddlewareBuilder) invalidate(ctx context.Context, qc *orm.QueryContext) {
+	_ = m.c.Set(ctx, m.versionKey(qc), newVersion(), 0)
+}
+
+func (m MiddlewareBuilder) versionKey(qc *orm.QueryContext) string {
+	return m.prefix + "version:" + qc.Model.TableName
+}
+
+func newVersion() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// decode 反序列化为 typ，例如 *T 或者 []*T
+func decode(val any, typ reflect.Type) (any, bool) {
+	data, ok := toBytes(val)
+	if !ok {
+		return nil, false
+	}
+	res := reflect.New(typ)
+	if err := json.Unmarshal(data, res.Interface()); err != nil {
+		return nil, false
+	}
+	return res.Elem().Interface(), true
+}
+
+// toBytes 本地缓存返回写入的 []byte，Redis 返回 string
+func toBytes(val any) ([]byte, bool) {
+	switch v := val.(type) {
+	case []byte:
+		return v, true
+	case string:
+		return []byte(v), true
+	default:
+		return nil, false
+	}
+}
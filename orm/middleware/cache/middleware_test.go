@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"regexp"
+	"scaffolding-go/cache"
+	"scaffolding-go/orm"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareBuilder(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	c := cache.NewBuildInMapCache(time.Minute)
+	db, err := orm.OpenDB(mockDB, orm.DBWithMiddleware(NewMiddlewareBuilder(c).Build()))
+	require.NoError(t, err)
+	ctx := context.Background()
+	cols := []string{"id", "name"}
+	querySQL := regexp.QuoteMeta("SELECT * FROM `test_model` WHERE `id` = ?;")
+
+	// 第二次从缓存里面读
+	mock.ExpectQuery(querySQL).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, "Tom"))
+	for i := 0; i < 2; i++ {
+		res, err := orm.NewSelector[TestModel](db).Where(orm.C("Id").Eq(1)).Get(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, &TestModel{Id: 1, Name: "Tom"}, res)
+	}
+
+	// 参数不同是不同的缓存
+	mock.ExpectQuery(querySQL).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(2, "Jerry"))
+	res, err := orm.NewSelector[TestModel](db).Where(orm.C("Id").Eq(2)).Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Jerry", res.Name)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `test_model`;")).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, "Tom").AddRow(2, "Jerry"))
+	for i := 0; i < 2; i++ {
+		ress, err := orm.NewSelector[TestModel](db).GetMulti(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*TestModel{{Id: 1, Name: "Tom"}, {Id: 2, Name: "Jerry"}}, ress)
+	}
+
+	// 修改之后缓存失效
+	mock.ExpectExec("UPDATE .*").WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, orm.NewUpdater[TestModel](db).Set(orm.Assign("Name", "Tom2")).
+		Where(orm.C("Id").Eq(1)).Exec(ctx).Err())
+	mock.ExpectQuery(querySQL).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, "Tom2"))
+	res, err = orm.NewSelector[TestModel](db).Where(orm.C("Id").Eq(1)).Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Tom2", res.Name)
+
+	// 事务里面不读缓存，也不写缓存
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE .*").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(querySQL).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, "Tom3"))
+	mock.ExpectRollback()
+	txCtx, tx, err := db.BeginTxV2(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, orm.NewUpdater[TestModel](db).Set(orm.Assign("Name", "Tom3")).
+		Where(orm.C("Id").Eq(1)).Exec(txCtx).Err())
+	res, err = orm.NewSelector[TestModel](tx).Where(orm.C("Id").Eq(1)).Get(txCtx)
+	require.NoError(t, err)
+	assert.Equal(t, "Tom3", res.Name)
+	require.NoError(t, tx.Rollback())
+	// 回滚之后缓存仍然有效
+	res, err = orm.NewSelector[TestModel](db).Where(orm.C("Id").Eq(1)).Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Tom2", res.Name)
+
+	// 提交之后缓存才失效
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE .*").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(querySQL).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, "Tom4"))
+	err = db.DoTx(ctx, func(ctx context.Context, tx *orm.Tx) error {
+		err := orm.NewUpdater[TestModel](tx).Set(orm.Assign("Name", "Tom4")).
+			Where(orm.C("Id").Eq(1)).Exec(ctx).Err()
+		if err != nil {
+			return err
+		}
+		// 提交之前别人仍然读到旧数据
+		res, err := orm.NewSelector[TestModel](db).Where(orm.C("Id").Eq(1)).Get(context.Background())
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, "Tom2", res.Name)
+		return nil
+	}, nil)
+	require.NoError(t, err)
+	res, err = orm.NewSelector[TestModel](db).Where(orm.C("Id").Eq(1)).Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Tom4", res.Name)
+
+	// 没有数据不缓存
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(querySQL).WithArgs(3).WillReturnRows(sqlmock.NewRows(cols))
+		_, err = orm.NewSelector[TestModel](db).Where(orm.C("Id").Eq(3)).Get(ctx)
+		assert.Equal(t, orm.ErrNoRows, err)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type TestModel struct {
+	Id   int64
+	Name string
+}
@@ -7,6 +7,8 @@ import (
 	"strings"
 )
 
+var errNoWhere = errors.New("不准执行没有 WHERE 的 delete 或者 update 语句")
+
 // 要强制查询语句
 // 1. SELECT、update、delete 必须要带 WHERE
 // 2. update 和 delete 必须要带 WHERE
@@ -20,7 +22,16 @@ func NewMiddlewareBuilder() *MiddlewareBuilder {
 func (m MiddlewareBuilder) Build() orm.Middleware {
 	return func(next orm.Handler) orm.Handler {
 		return func(ctx context.Context, qc *orm.QueryContext) *orm.QueryResult {
-			if qc.Type == "SELECT" || qc.Type == "INSERT" {
+			switch qc.Type {
+			case "SELECT", "ITER", "INSERT":
+				return next(ctx, qc)
+			case "UPDATE", "DELETE":
+				// 看用户的条件，软删除生成的 SQL 里面总是有 WHERE
+				if qc.FullTable {
+					return &orm.QueryResult{
+						Err: errNoWhere,
+					}
+				}
 				return next(ctx, qc)
 			}
 			q, err := qc.Builder.Build()
@@ -31,7 +42,7 @@ func (m MiddlewareBuilder) Build() orm.Middleware {
 			}
 			if !strings.Contains(q.SQL, "WHERE") {
 				return &orm.QueryResult{
-					Err: errors.New("不准执行没有 WHERE 的 delete 或者 update 语句"),
+					Err: errNoWhere,
 				}
 			}
 			return next(ctx, qc)
@@ -0,0 +1,40 @@
+package slowquery
+
+import (
+	"context"
+	"scaffolding-go/orm"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareBuilder(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := orm.OpenDB(mockDB, orm.DBWithMiddleware(NewMiddlewareBuilder().Build()))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// 组合查询和 SELECT 一样，成员没有 WHERE 也可以执行
+	mock.ExpectQuery("SELECT .* UNION SELECT .*").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	u := orm.Union(orm.NewSelector[TestModel](db), orm.NewSelector[TestModel](db))
+	res, err := orm.RawQueryOf[TestModel](db, u).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*TestModel{{Id: 1}}, res)
+
+	// 原生查询没有 WHERE
+	_, err = orm.RawQuery[TestModel](db, "SELECT * FROM `test_model`").GetMulti(ctx)
+	assert.Equal(t, errNoWhere, err)
+
+	// 没有 WHERE 的 DELETE
+	err = orm.NewDeleter[TestModel](db).Exec(ctx).Err()
+	assert.Equal(t, errNoWhere, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type TestModel struct {
+	Id int64
+}
@@ -0,0 +1,108 @@
+// migrate 是版本化迁移的命令行工具
+//
+//	migrate -driver mysql -dsn "root:root@tcp(localhost:3306)/db?multiStatements=true" -dir ./migrations up
+//	migrate -driver sqlite3 -dsn ./test.db -dir ./migrations down 1
+//	migrate -driver sqlite3 -dsn ./test.db -dir ./migrations goto 3
+//	migrate -driver sqlite3 -dsn ./test.db -dir ./migrations status
+//
+// 只内置了 MySQL 和 SQLite 的驱动，其它数据库可以参考这里用 migrate 包写一个
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"scaffolding-go/orm"
+	"scaffolding-go/orm/migrate"
+	"strconv"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var dialects = map[string]orm.Dialect{
+	"mysql":   orm.DialectMySQL,
+	"sqlite3": orm.DialectSQLite,
+}
+
+func main() {
+	driver := flag.String("driver", "mysql", "数据库驱动，mysql 或者 sqlite3")
+	dsn := flag.String("dsn", "", "数据源")
+	dir := flag.String("dir", "migrations", "迁移文件所在的目录")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(),
+			"用法: %s [flags] up | down [n] | goto version | version | status\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if err := run(*driver, *dsn, *dir, flag.Args()); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(driver, dsn, dir string, args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		return fmt.Errorf("缺少命令")
+	}
+	dialect, ok := dialects[driver]
+	if !ok {
+		return fmt.Errorf("不支持的驱动 %s", driver)
+	}
+	db, err := orm.Open(driver, dsn, orm.DBWithDialect(dialect))
+	if err != nil {
+		return err
+	}
+	m, err := migrate.New(db, os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("非法的回滚数量 %s", args[1])
+			}
+		}
+		return m.Down(ctx, n)
+	case "goto":
+		if len(args) < 2 {
+			return fmt.Errorf("缺少版本")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("非法的版本 %s", args[1])
+		}
+		return m.Goto(ctx, version)
+	case "version":
+		version, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version: %d\n", version)
+		return nil
+	case "status":
+		sts, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range sts {
+			applied := "pending"
+			if st.Applied {
+				applied = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d\t%s\t%s\n", st.Version, st.Name, applied)
+		}
+		return nil
+	default:
+		flag.Usage()
+		return fmt.Errorf("未知的命令 %s", args[0])
+	}
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Migration 代表一个版本的迁移
+// 由 NNNN_name.up.sql 和 NNNN_name.down.sql 两个文件组成，down 文件可以没有
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum up 文件内容的摘要，用于发现已经执行过的文件被修改了
+// down 文件还没有执行过，修改它不会影响已经执行的结果，所以不参与计算
+func (m *Migration) Checksum() string {
+	h := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(h[:])
+}
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Load 从 fsys 的根目录加载迁移文件，按照版本从小到大排序
+// 不符合 NNNN_name.up.sql 格式的文件会被忽略
+// 子目录可以用 fs.Sub 转换
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	migrations := make(map[int64]*Migration, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		segs := fileNamePattern.FindStringSubmatch(entry.Name())
+		if segs == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(segs[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFileName, entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, ok := migrations[version]
+		if !ok {
+			m = &Migration{Version: version, Name: segs[2]}
+			migrations[version] = m
+		} else if m.Name != segs[2] {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+		}
+		if segs[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	res := make([]*Migration, 0, len(migrations))
+	for _, m := range migrations {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%w: %d_%s", ErrMissingUpFile, m.Version, m.Name)
+		}
+		res = append(res, m)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+	return res, nil
+}
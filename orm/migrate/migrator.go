@@ -0,0 +1,236 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"scaffolding-go/orm"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidFileName  = errors.New("migrate: 非法的迁移文件名")
+	ErrDuplicateVersion = errors.New("migrate: 迁移版本重复")
+	ErrMissingUpFile    = errors.New("migrate: 缺少 up 文件")
+	ErrMissingDownFile  = errors.New("migrate: 缺少 down 文件，无法回滚")
+	// ErrChecksumMismatch 代表已经执行过的迁移文件被修改了
+	ErrChecksumMismatch = errors.New("migrate: 迁移文件和执行时的不一致")
+	// ErrMissingMigration 代表数据库里面记录执行过的迁移，找不到对应的文件
+	ErrMissingMigration = errors.New("migrate: 找不到已经执行的迁移文件")
+	ErrUnknownVersion   = errors.New("migrate: 未知的版本")
+)
+
+// record 是 schema_migrations 表里面的一行
+type record struct {
+	Version  int64  `orm:"pk"`
+	Name     string `orm:"size=255,not_null"`
+	Checksum string `orm:"size=64,not_null"`
+	// 毫秒时间戳
+	AppliedAt int64 `orm:"not_null"`
+}
+
+func (record) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 代表一个迁移的执行情况
+type Status struct {
+	*Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator 执行版本化的迁移
+// 每一个迁移都在一个单独的事务里面执行，并且在同一个事务里面记录到 schema_migrations
+// 注意 MySQL 的 DDL 会隐式提交事务，所以一个迁移文件最好只做一件事
+// 迁移文件会作为一个整体执行，MySQL 的 DSN 需要开启 multiStatements
+type Migrator struct {
+	db         *orm.DB
+	migrations []*Migration
+	versions   map[int64]*Migration
+
+	// schema_migrations 是否已经创建了，参考 initTable
+	mu     sync.Mutex
+	inited bool
+}
+
+// New 从 fsys 加载迁移文件，参考 Load
+func New(db *orm.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[int64]*Migration, len(migrations))
+	for _, m := range migrations {
+		versions[m.Version] = m
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		versions:   versions,
+	}, nil
+}
+
+// Up 执行所有还没有执行的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for _, mg := range m.migrations {
+		if _, ok := applied[mg.Version]; ok {
+			continue
+		}
+		if err = m.up(ctx, mg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down 回滚最近执行的 n 个迁移
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for i := len(m.migrations) - 1; i >= 0 && n > 0; i-- {
+		mg := m.migrations[i]
+		if _, ok := applied[mg.Version]; !ok {
+			continue
+		}
+		if err = m.down(ctx, mg); err != nil {
+			return err
+		}
+		n--
+	}
+	return nil
+}
+
+// Goto 迁移到指定的版本
+// 回滚比 version 大的迁移，执行不超过 version 的迁移，version 为 0 代表全部回滚
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	if _, ok := m.versions[version]; !ok && version != 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mg := m.migrations[i]
+		if _, ok := applied[mg.Version]; !ok || mg.Version <= version {
+			continue
+		}
+		if err = m.down(ctx, mg); err != nil {
+			return err
+		}
+	}
+	for _, mg := range m.migrations {
+		if _, ok := applied[mg.Version]; ok || mg.Version > version {
+			continue
+		}
+		if err = m.up(ctx, mg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Version 返回已经执行的最大版本，没有执行过任何迁移的时候返回 0
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var res int64
+	for version := range applied {
+		res = max(res, version)
+	}
+	return res, nil
+}
+
+// Status 返回所有迁移的执行情况，按照版本从小到大排序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		st := Status{Migration: mg}
+		if r, ok := applied[mg.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = time.UnixMilli(r.AppliedAt)
+		}
+		res = append(res, st)
+	}
+	return res, nil
+}
+
+// initTable 第一次使用的时候创建 schema_migrations
+// 不用 sync.Once，这样失败了下一次还可以重试
+func (m *Migrator) initTable(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.inited {
+		return nil
+	}
+	if _, err := orm.NewMigrator(m.db).AutoMigrate(ctx, &record{}); err != nil {
+		return err
+	}
+	m.inited = true
+	return nil
+}
+
+// applied 查询已经执行的迁移，并且检查文件有没有被修改
+func (m *Migrator) applied(ctx context.Context) (map[int64]*record, error) {
+	if err := m.initTable(ctx); err != nil {
+		return nil, err
+	}
+	records, err := orm.NewSelector[record](m.db).GetMulti(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]*record, len(records))
+	for _, r := range records {
+		mg, ok := m.versions[r.Version]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d_%s", ErrMissingMigration, r.Version, r.Name)
+		}
+		if mg.Checksum() != r.Checksum {
+			return nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, r.Version, r.Name)
+		}
+		res[r.Version] = r
+	}
+	return res, nil
+}
+
+func (m *Migrator) up(ctx context.Context, mg *Migration) error {
+	return m.db.DoTx(ctx, func(ctx context.Context, tx *orm.Tx) error {
+		if err := orm.RawQuery[record](tx, mg.Up).Exec(ctx).Err(); err != nil {
+			return err
+		}
+		return orm.NewInserter[record](tx).Values(&record{
+			Version:   mg.Version,
+			Name:      mg.Name,
+			Checksum:  mg.Checksum(),
+			AppliedAt: time.Now().UnixMilli(),
+		}).Exec(ctx).Err()
+	}, nil)
+}
+
+func (m *Migrator) down(ctx context.Context, mg *Migration) error {
+	if mg.Down == "" {
+		return fmt.Errorf("%w: %d_%s", ErrMissingDownFile, mg.Version, mg.Name)
+	}
+	return m.db.DoTx(ctx, func(ctx context.Context, tx *orm.Tx) error {
+		if err := orm.RawQuery[record](tx, mg.Down).Exec(ctx).Err(); err != nil {
+			return err
+		}
+		return orm.NewDeleter[record](tx).Where(orm.C("Version").Eq(mg.Version)).
+			Exec(ctx).Err()
+	}, nil)
+}
@@ -0,0 +1,204 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"scaffolding-go/orm"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fsys     fstest.MapFS
+		wantVers []int64
+		wantErr  error
+	}{
+		{
+			name: "load",
+			fsys: fstest.MapFS{
+				"0002_add_age.up.sql":       {Data: []byte("ALTER TABLE user ADD COLUMN age INTEGER;")},
+				"0001_create_user.up.sql":   {Data: []byte("CREATE TABLE user(id INTEGER);")},
+				"0001_create_user.down.sql": {Data: []byte("DROP TABLE user;")},
+				"README.md":                 {Data: []byte("ignored")},
+				"sub/0003_x.up.sql":         {Data: []byte("ignored")},
+			},
+			wantVers: []int64{1, 2},
+		},
+		{
+			name: "missing up",
+			fsys: fstest.MapFS{
+				"0001_create_user.down.sql": {Data: []byte("DROP TABLE user;")},
+			},
+			wantErr: ErrMissingUpFile,
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"0001_create_user.up.sql":  {Data: []byte("CREATE TABLE user(id INTEGER);")},
+				"0001_create_order.up.sql": {Data: []byte("CREATE TABLE order(id INTEGER);")},
+			},
+			wantErr: ErrDuplicateVersion,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ms, err := Load(tc.fsys)
+			assert.ErrorIs(t, err, tc.wantErr)
+			if err != nil {
+				return
+			}
+			vers := make([]int64, 0, len(ms))
+			for _, m := range ms {
+				vers = append(vers, m.Version)
+			}
+			assert.Equal(t, tc.wantVers, vers)
+		})
+	}
+}
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_user.up.sql":   {Data: []byte("CREATE TABLE user(id INTEGER PRIMARY KEY);")},
+		"0001_create_user.down.sql": {Data: []byte("DROP TABLE user;")},
+		"0002_add_age.up.sql":       {Data: []byte("ALTER TABLE user ADD COLUMN age INTEGER;")},
+		"0002_add_age.down.sql":     {Data: []byte("ALTER TABLE user DROP COLUMN age;")},
+		"0003_create_order.up.sql": {Data: []byte("CREATE TABLE `order`(id INTEGER);" +
+			"CREATE INDEX idx_order_id ON `order`(id);")},
+		"0003_create_order.down.sql": {Data: []byte("DROP TABLE `order`;")},
+	}
+}
+
+func openDB(t *testing.T) *orm.DB {
+	db, err := orm.Open("sqlite3",
+		fmt.Sprintf("file:%s.db?cache=shared&mode=memory", t.Name()),
+		orm.DBWithDialect(orm.DialectSQLite))
+	require.NoError(t, err)
+	return db
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	m, err := New(db, testFS())
+	require.NoError(t, err)
+
+	require.NoError(t, m.Up(ctx))
+	assertVersion(t, m, 3)
+	assertTables(t, db, "order", "schema_migrations", "user")
+	// 重复执行没有影响
+	require.NoError(t, m.Up(ctx))
+	assertVersion(t, m, 3)
+
+	require.NoError(t, m.Down(ctx, 1))
+	assertVersion(t, m, 2)
+	assertTables(t, db, "schema_migrations", "user")
+
+	require.NoError(t, m.Goto(ctx, 1))
+	assertVersion(t, m, 1)
+	sts, err := m.Status(ctx)
+	require.NoError(t, err)
+	applied := make([]bool, 0, len(sts))
+	for _, st := range sts {
+		applied = append(applied, st.Applied)
+	}
+	assert.Equal(t, []bool{true, false, false}, applied)
+
+	require.NoError(t, m.Goto(ctx, 3))
+	assertVersion(t, m, 3)
+
+	assert.ErrorIs(t, m.Goto(ctx, 4), ErrUnknownVersion)
+
+	require.NoError(t, m.Goto(ctx, 0))
+	assertVersion(t, m, 0)
+	assertTables(t, db, "schema_migrations")
+}
+
+func TestMigrator_InitTableOnce(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	m, err := New(db, testFS())
+	require.NoError(t, err)
+	assertVersion(t, m, 0)
+	assertTables(t, db, "schema_migrations")
+
+	// 只在第一次使用的时候创建，删掉之后不会再创建
+	require.NoError(t, orm.RawQuery[record](db, "DROP TABLE schema_migrations").Exec(ctx).Err())
+	_, err = m.Version(ctx)
+	assert.ErrorContains(t, err, "no such table")
+}
+
+func TestMigrator_Drift(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	m, err := New(db, testFS())
+	require.NoError(t, err)
+	require.NoError(t, m.Goto(ctx, 2))
+
+	fsys := testFS()
+	fsys["0002_add_age.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE user ADD COLUMN age BIGINT;")}
+	m, err = New(db, fsys)
+	require.NoError(t, err)
+	assert.ErrorIs(t, m.Up(ctx), ErrChecksumMismatch)
+	_, err = m.Version(ctx)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	fsys = testFS()
+	delete(fsys, "0001_create_user.up.sql")
+	delete(fsys, "0001_create_user.down.sql")
+	m, err = New(db, fsys)
+	require.NoError(t, err)
+	assert.ErrorIs(t, m.Up(ctx), ErrMissingMigration)
+
+	// 修改已经执行过的迁移的 down 文件是可以的
+	fsys = testFS()
+	fsys["0002_add_age.down.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE user DROP COLUMN age;\n")}
+	m, err = New(db, fsys)
+	require.NoError(t, err)
+	require.NoError(t, m.Goto(ctx, 1))
+	assertVersion(t, m, 1)
+}
+
+func TestMigrator_Failed(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	fsys := testFS()
+	fsys["0002_add_age.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE missing ADD COLUMN age INTEGER;")}
+	delete(fsys, "0001_create_user.down.sql")
+	m, err := New(db, fsys)
+	require.NoError(t, err)
+
+	// 第二个迁移失败，第一个迁移依旧生效
+	assert.Error(t, m.Up(ctx))
+	assertVersion(t, m, 1)
+
+	assert.ErrorIs(t, m.Down(ctx, 1), ErrMissingDownFile)
+	assertVersion(t, m, 1)
+}
+
+func assertVersion(t *testing.T, m *Migrator, want int64) {
+	version, err := m.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, want, version)
+}
+
+type sqliteTable struct {
+	Name string
+}
+
+func assertTables(t *testing.T, db *orm.DB, want ...string) {
+	tables, err := orm.RawQuery[sqliteTable](db,
+		"SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name").
+		GetMulti(context.Background())
+	require.NoError(t, err)
+	names := make([]string, 0, len(tables))
+	for _, tbl := range tables {
+		names = append(names, tbl.Name)
+	}
+	assert.Equal(t, want, names)
+}
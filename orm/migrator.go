@@ -0,0 +1,222 @@
+package orm
+
+import (
+	"context"
+	"scaffolding-go/orm/model"
+)
+
+type MigratorOption func(m *Migrator)
+
+// Migrator 根据模型的元数据迁移表结构
+// 表不存在的时候建表；表已经存在的时候只增加缺少的列和索引
+// 不会删除或者修改已有的列，这些变更应该用 migrate 包的版本化迁移
+type Migrator struct {
+	core
+	sess   Session
+	dryRun bool
+}
+
+func NewMigrator(sess Session, opts ...MigratorOption) *Migrator {
+	res := &Migrator{
+		core: sess.getCore(),
+		sess: sess,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// MigratorWithDryRun 只生成 SQL，不执行
+// 比较表结构的查询依旧会执行
+func MigratorWithDryRun() MigratorOption {
+	return func(m *Migrator) {
+		m.dryRun = true
+	}
+}
+
+// AutoMigrate 迁移 entities 对应的表，entities 是结构体指针
+// 返回执行了的 SQL，dry run 的时候返回需要执行的 SQL
+func (m *Migrator) AutoMigrate(ctx context.Context, entities ...any) ([]string, error) {
+	var res []string
+	for _, entity := range entities {
+		mdl, err := m.r.Get(entity)
+		if err != nil {
+			return res, err
+		}
+		stmts, err := m.migrate(ctx, mdl)
+		if err != nil {
+			return res, err
+		}
+		if m.dryRun {
+			res = append(res, stmts...)
+			continue
+		}
+		for _, stmt := range stmts {
+			if _, err = m.sess.execContext(ctx, stmt); err != nil {
+				return res, err
+			}
+			res = append(res, stmt)
+		}
+	}
+	return res, nil
+}
+
+// CreateTableSQL 返回模型的建表语句，包括建索引的语句
+func (m *Migrator) CreateTableSQL(entity any) ([]string, error) {
+	mdl, err := m.r.Get(entity)
+	if err != nil {
+		return nil, err
+	}
+	return m.createTable(mdl)
+}
+
+func (m *Migrator) migrate(ctx context.Context, mdl *model.Model) ([]string, error) {
+	query, args := m.dialect.columnsQuery(mdl.TableName)
+	cols, err := m.queryNames(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) == 0 {
+		return m.createTable(mdl)
+	}
+
+	var res []string
+	for _, fd := range mdl.Fields {
+		if _, ok := cols[fd.ColName]; ok {
+			continue
+		}
+		b := m.newBuilder()
+		b.sb.WriteString("ALTER TABLE ")
+		b.quote(mdl.TableName)
+		b.sb.WriteString(" ADD COLUMN ")
+		if err = m.buildColumnDef(b, fd); err != nil {
+			return nil, err
+		}
+		b.sb.WriteByte(';')
+		res = append(res, b.sb.String())
+	}
+
+	query, args = m.dialect.indexesQuery(mdl.TableName)
+	if query == "" {
+		return res, nil
+	}
+	indexes, err := m.queryNames(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range mdl.Indexes {
+		if _, ok := indexes[idx.Name]; ok {
+			continue
+		}
+		res = append(res, m.createIndex(mdl, idx))
+	}
+	return res, nil
+}
+
+func (m *Migrator) createTable(mdl *model.Model) ([]string, error) {
+	b := m.newBuilder()
+	b.sb.WriteString("CREATE TABLE ")
+	b.quote(mdl.TableName)
+	b.sb.WriteString(" (")
+	for i, fd := range mdl.Fields {
+		if i > 0 {
+			b.sb.WriteString(", ")
+		}
+		if err := m.buildColumnDef(b, fd); err != nil {
+			return nil, err
+		}
+	}
+	if len(mdl.PrimaryKeys) > 0 {
+		b.sb.WriteString(", PRIMARY KEY (")
+		for i, pk := range mdl.PrimaryKeys {
+			if i > 0 {
+				b.sb.WriteByte(',')
+			}
+			b.quote(pk.ColName)
+		}
+		b.sb.WriteByte(')')
+	}
+	b.sb.WriteString(");")
+
+	res := make([]string, 0, len(mdl.Indexes)+1)
+	res = append(res, b.sb.String())
+	for _, idx := range mdl.Indexes {
+		res = append(res, m.createIndex(mdl, idx))
+	}
+	return res, nil
+}
+
+// buildColumnDef 构造列定义，例如 `age` INT NOT NULL DEFAULT 0
+func (m *Migrator) buildColumnDef(b *builder, fd *model.Field) error {
+	typ, err := m.dialect.columnType(fd)
+	if err != nil {
+		return err
+	}
+	b.quote(fd.ColName)
+	b.sb.WriteByte(' ')
+	b.sb.WriteString(typ)
+	if fd.NotNull || fd.PrimaryKey {
+		b.sb.WriteString(" NOT NULL")
+	}
+	if fd.Default != nil {
+		b.sb.WriteString(" DEFAULT ")
+		// 默认值原样写入，这样可以使用 CURRENT_TIMESTAMP 之类的表达式
+		if *fd.Default == "" {
+			b.sb.WriteString("''")
+		} else {
+			b.sb.WriteString(*fd.Default)
+		}
+	}
+	if fd.Unique {
+		b.sb.WriteString(" UNIQUE")
+	}
+	return nil
+}
+
+// createIndex 使用 CREATE INDEX，MySQL、SQLite 和 PostgreSQL 都支持
+func (m *Migrator) createIndex(mdl *model.Model, idx *model.Index) string {
+	b := m.newBuilder()
+	b.sb.WriteString("CREATE INDEX ")
+	b.quote(idx.Name)
+	b.sb.WriteString(" ON ")
+	b.quote(mdl.TableName)
+	b.sb.WriteString(" (")
+	for i, fd := range idx.Fields {
+		if i > 0 {
+			b.sb.WriteByte(',')
+		}
+		b.quote(fd.ColName)
+	}
+	b.sb.WriteString(");")
+	return b.sb.String()
+}
+
+// queryNames 执行只有一列的元数据查询
+func (m *Migrator) queryNames(ctx context.Context, query string, args []any) (map[string]struct{}, error) {
+	b := m.newBuilder()
+	b.buildRaw(Raw(query, args...))
+	rows, err := m.sess.queryContext(ctx, b.sb.String(), b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	res := make(map[string]struct{})
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		res[name] = struct{}{}
+	}
+	return res, rows.Err()
+}
+
+func (m *Migrator) newBuilder() *builder {
+	return &builder{
+		core:   m.core,
+		quoter: m.dialect.quoter(),
+	}
+}
@@ -0,0 +1,197 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"regexp"
+	"scaffolding-go/orm/internal/errs"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MigrateUser struct {
+	Id        int64  `orm:"pk,auto_increment"`
+	Email     string `orm:"size=128,not_null,unique"`
+	Name      string `orm:"size=64,default='',index=idx_name_age"`
+	Age       *int8  `orm:"index=idx_name_age"`
+	Balance   float64
+	Nickname  sql.NullString
+	Avatar    []byte
+	Active    bool `orm:"default=TRUE"`
+	CreatedAt time.Time
+	Cache     string `orm:"-"`
+}
+
+func TestMigrator_CreateTableSQL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		dialect  Dialect
+		entity   any
+		wantSQLs []string
+		wantErr  error
+	}{
+		{
+			name:    "mysql",
+			dialect: DialectMySQL,
+			entity:  &MigrateUser{},
+			wantSQLs: []string{
+				"CREATE TABLE `migrate_user` (`id` BIGINT AUTO_INCREMENT NOT NULL, " +
+					"`email` VARCHAR(128) NOT NULL UNIQUE, `name` VARCHAR(64) DEFAULT '', " +
+					"`age` TINYINT, `balance` DOUBLE, `nickname` VARCHAR(255), `avatar` BLOB, " +
+					"`active` TINYINT(1) DEFAULT TRUE, `created_at` DATETIME, PRIMARY KEY (`id`));",
+				"CREATE INDEX `idx_name_age` ON `migrate_user` (`name`,`age`);",
+			},
+		},
+		{
+			name:    "sqlite",
+			dialect: DialectSQLite,
+			entity:  &MigrateUser{},
+			wantSQLs: []string{
+				"CREATE TABLE `migrate_user` (`id` INTEGER NOT NULL, " +
+					"`email` TEXT NOT NULL UNIQUE, `name` TEXT DEFAULT '', " +
+					"`age` INTEGER, `balance` REAL, `nickname` TEXT, `avatar` BLOB, " +
+					"`active` INTEGER DEFAULT TRUE, `created_at` DATETIME, PRIMARY KEY (`id`));",
+				"CREATE INDEX `idx_name_age` ON `migrate_user` (`name`,`age`);",
+			},
+		},
+		{
+			name:    "postgres",
+			dialect: DialectPostgreSQL,
+			entity:  &MigrateUser{},
+			wantSQLs: []string{
+				`CREATE TABLE "migrate_user" ("id" BIGSERIAL NOT NULL, ` +
+					`"email" VARCHAR(128) NOT NULL UNIQUE, "name" VARCHAR(64) DEFAULT '', ` +
+					`"age" SMALLINT, "balance" DOUBLE PRECISION, "nickname" TEXT, "avatar" BYTEA, ` +
+					`"active" BOOLEAN DEFAULT TRUE, "created_at" TIMESTAMP, PRIMARY KEY ("id"));`,
+				`CREATE INDEX "idx_name_age" ON "migrate_user" ("name","age");`,
+			},
+		},
+		{
+			name:    "standard",
+			dialect: DialectStandardSQL,
+			entity:  &TestModel{},
+			wantSQLs: []string{
+				`CREATE TABLE "test_model" ("id" BIGINT, "first_name" VARCHAR(255), ` +
+					`"age" SMALLINT, "last_name" VARCHAR(255));`,
+			},
+		},
+		{
+			name:    "unsupported type",
+			dialect: DialectMySQL,
+			entity: func() any {
+				type BadModel struct {
+					Tags []string
+				}
+				return &BadModel{}
+			}(),
+			wantErr: errs.NewErrUnsupportedColumnType("Tags", reflect.TypeOf([]string{})),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := memoryDB(t, DBWithDialect(tc.dialect))
+			sqls, err := NewMigrator(db).CreateTableSQL(tc.entity)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantSQLs, sqls)
+		})
+	}
+}
+
+type migrateOrderV1 struct {
+	Id     int64 `orm:"pk,auto_increment"`
+	UserId int64
+}
+
+func (migrateOrderV1) TableName() string {
+	return "migrate_order"
+}
+
+type migrateOrderV2 struct {
+	Id     int64 `orm:"pk,auto_increment"`
+	UserId int64 `orm:"index=idx_user_id"`
+	Amount int64 `orm:"not_null,default=0"`
+}
+
+func (migrateOrderV2) TableName() string {
+	return "migrate_order"
+}
+
+func TestMigrator_AutoMigrate(t *testing.T) {
+	db, err := Open("sqlite3", "file:migrator.db?cache=shared&mode=memory",
+		DBWithDialect(DialectSQLite))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	sqls, err := NewMigrator(db).AutoMigrate(ctx, &migrateOrderV1{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"CREATE TABLE `migrate_order` (`id` INTEGER NOT NULL, `user_id` INTEGER, PRIMARY KEY (`id`));",
+	}, sqls)
+
+	// 已经是最新的了
+	sqls, err = NewMigrator(db).AutoMigrate(ctx, &migrateOrderV1{})
+	require.NoError(t, err)
+	assert.Empty(t, sqls)
+
+	wantSQLs := []string{
+		"ALTER TABLE `migrate_order` ADD COLUMN `amount` INTEGER NOT NULL DEFAULT 0;",
+		"CREATE INDEX `idx_user_id` ON `migrate_order` (`user_id`);",
+	}
+	// dry run 不会执行
+	for i := 0; i < 2; i++ {
+		sqls, err = NewMigrator(db, MigratorWithDryRun()).AutoMigrate(ctx, &migrateOrderV2{})
+		require.NoError(t, err)
+		assert.Equal(t, wantSQLs, sqls)
+	}
+
+	sqls, err = NewMigrator(db).AutoMigrate(ctx, &migrateOrderV2{})
+	require.NoError(t, err)
+	assert.Equal(t, wantSQLs, sqls)
+
+	sqls, err = NewMigrator(db).AutoMigrate(ctx, &migrateOrderV2{})
+	require.NoError(t, err)
+	assert.Empty(t, sqls)
+
+	// 自增主键可以正常使用
+	order := &migrateOrderV2{UserId: 12}
+	res := NewInserter[migrateOrderV2](db).Values(order).BackfillPK().Exec(ctx)
+	require.NoError(t, res.Err())
+	assert.Equal(t, int64(1), order.Id)
+}
+
+func TestMigrator_MySQL(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	columnsQuery := regexp.QuoteMeta("SELECT COLUMN_NAME FROM information_schema.COLUMNS " +
+		"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?")
+	indexesQuery := regexp.QuoteMeta("SELECT DISTINCT INDEX_NAME FROM information_schema.STATISTICS " +
+		"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?")
+
+	mock.ExpectQuery(columnsQuery).WithArgs("migrate_order").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("user_id"))
+	mock.ExpectQuery(indexesQuery).WithArgs("migrate_order").
+		WillReturnRows(sqlmock.NewRows([]string{"INDEX_NAME"}).AddRow("PRIMARY"))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE `migrate_order` ADD COLUMN `amount` BIGINT NOT NULL DEFAULT 0;")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX `idx_user_id` ON `migrate_order` (`user_id`);")).
+		WillReturnError(errors.New("db error"))
+
+	sqls, err := NewMigrator(db).AutoMigrate(context.Background(), &migrateOrderV2{})
+	assert.Equal(t, errors.New("db error"), err)
+	assert.Equal(t, []string{
+		"ALTER TABLE `migrate_order` ADD COLUMN `amount` BIGINT NOT NULL DEFAULT 0;",
+	}, sqls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
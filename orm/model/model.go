@@ -1,18 +1,66 @@
 package model
 
 import (
+	"database/sql"
 	"errors"
 	"reflect"
 	"scaffolding-go/orm/internal/errs"
+	"scaffolding-go/orm/sharding"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 )
 
 const (
 	tagKeyColumn = "column"
+	// tagKeyPK 标记主键，例如 orm:"pk" 或者 orm:"pk=true"
+	tagKeyPK = "pk"
+	// tagKeyAutoIncrement 标记由数据库生成的自增列，例如 orm:"pk,auto_increment"
+	tagKeyAutoIncrement = "auto_increment"
+	// tagKeyAuto 是 auto_increment 的简写
+	tagKeyAuto    = "auto"
+	tagKeySize    = "size"
+	tagKeyDefault = "default"
+	tagKeyNotNull = "not_null"
+	tagKeyUnique  = "unique"
+	// tagKeyIndex 标记普通索引，多个字段使用同一个名字就是联合索引
+	tagKeyIndex = "index"
+	// tagKeySoftDelete 标记软删除的字段，例如 orm:"soft_delete"
+	tagKeySoftDelete = "soft_delete"
+	// tagKeyAutoCreateTime 插入的时候自动填充当前时间，例如 orm:"auto_create_time"
+	tagKeyAutoCreateTime = "auto_create_time"
+	// tagKeyAutoUpdateTime 插入和更新的时候自动填充当前时间，例如 orm:"auto_update_time"
+	tagKeyAutoUpdateTime = "auto_update_time"
+	// tagKeyVersion 标记乐观锁的版本号字段，例如 orm:"version"
+	tagKeyVersion = "version"
+	// 兼容 GORM 的写法
+	tagKeyAutoCreateTimeCamel = "autoCreateTime"
+	tagKeyAutoUpdateTimeCamel = "autoUpdateTime"
+	// tagIgnore 代表忽略这个字段，例如 orm:"-"
+	tagIgnore = "-"
 )
 
+// boolTagKeys 可以只写 key 的标签，例如 orm:"pk,not_null"
+var boolTagKeys = map[string]struct{}{
+	tagKeyPK:            {},
+	tagKeyAutoIncrement: {},
+	tagKeyAuto:          {},
+	tagKeyNotNull:       {},
+	tagKeyUnique:        {},
+	tagKeySoftDelete:    {},
+	tagKeyVersion:       {},
+	tagKeyHasOne:        {},
+	tagKeyHasMany:       {},
+	tagKeyBelongsTo:     {},
+
+	tagKeyAutoCreateTime:      {},
+	tagKeyAutoUpdateTime:      {},
+	tagKeyAutoCreateTimeCamel: {},
+	tagKeyAutoUpdateTimeCamel: {},
+}
+
 type Registry interface {
 	Get(val any) (*Model, error)
 	Register(val any, opts ...Option) (*Model, error)
@@ -25,6 +73,36 @@ type Model struct {
 	FieldMap map[string]*Field
 	// 列名到字段定义的映射
 	ColumnMap map[string]*Field
+
+	// 主键，按照字段定义的顺序，联合主键会有多个
+	PrimaryKeys []*Field
+	// 索引，按照第一次出现的顺序
+	Indexes []*Index
+	// 被 orm:"-" 忽略的字段名
+	IgnoredFields []string
+	// 软删除的字段，没有的时候为 nil
+	SoftDelete *Field
+	// 乐观锁的版本号字段，没有的时候为 nil
+	Version *Field
+	// 关联关系，字段名到关联关系的映射
+	Relations map[string]*Relation
+	// 分片算法，没有分片的时候为 nil
+	Sharding sharding.Algorithm
+}
+
+// EmbeddedPtr 代表一个匿名嵌入的结构体指针，例如 *BaseModel
+type EmbeddedPtr struct {
+	// 指针字段相对于外层结构体的偏移量
+	Offset uintptr
+	// 指针指向的结构体类型
+	Typ reflect.Type
+}
+
+// Index 代表 orm:"index=idx_name" 声明的索引
+type Index struct {
+	Name string
+	// 按照字段定义的顺序
+	Fields []*Field
 }
 
 type Option func(m *Model) error
@@ -38,7 +116,31 @@ type Field struct {
 	Typ reflect.Type
 
 	// 字段相对于结构体本身的偏移量
+	// 通过嵌入指针访问的字段，是相对于最内层指针指向的结构体的偏移量
 	Offset uintptr
+	// 访问字段需要经过的嵌入指针，从外到内，没有的时候为 nil
+	EmbeddedPtrs []EmbeddedPtr
+
+	// 是否是主键
+	PrimaryKey bool
+	// 是否是数据库生成的自增列，插入之后可以回填
+	AutoIncrement bool
+	// 列的长度，例如 VARCHAR(size)，0 代表没有设置
+	Size int
+	// 列的默认值，nil 代表没有设置
+	Default *string
+	NotNull bool
+	Unique  bool
+	// 所属的索引名字
+	Index string
+	// 是否是软删除的字段，NULL 代表没有删除
+	SoftDelete bool
+	// 插入的时候，零值会被填充为当前时间
+	AutoCreateTime bool
+	// 插入和更新的时候，会被填充为当前时间
+	AutoUpdateTime bool
+	// 是否是乐观锁的版本号
+	Version bool
 }
 
 //var models = map[reflect.Type]*Model{}
@@ -104,31 +206,42 @@ func (r *registry) Register(entity any, opts ...Option) (*Model, error) {
 		return nil, errors.New("orm: 只支持指向结构体的一级指针")
 	}
 	elemType := typ.Elem()
-	numField := elemType.NumField()
-	fieldMap := make(map[string]*Field, numField)
-	columnMap := make(map[string]*Field, numField)
-	fields := make([]*Field, 0, numField)
-	for i := 0; i < numField; i++ {
-		fd := elemType.Field(i)
-		pair, err := r.parseTag(fd.Tag)
-		if err != nil {
-			return nil, err
+	fields, ignored, relations, err := r.parseFields(elemType, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	fieldMap := make(map[string]*Field, len(fields))
+	columnMap := make(map[string]*Field, len(fields))
+	var pks []*Field
+	var indexes []*Index
+	var softDelete, version *Field
+	for _, fdMeta := range fields {
+		if old, ok := fieldMap[fdMeta.GoName]; ok {
+			return nil, errs.NewErrDuplicateField(fdMeta.GoName, old.ColName, fdMeta.ColName)
 		}
-		colName, _ := pair[tagKeyColumn]
-		if colName == "" {
-			// 用户没有设置
-			colName = underscoreName(fd.Name)
+		if old, ok := columnMap[fdMeta.ColName]; ok {
+			return nil, errs.NewErrDuplicateColumn(fdMeta.ColName, old.GoName, fdMeta.GoName)
 		}
-		fdMeta := &Field{
-			GoName:  fd.Name,
-			ColName: colName,
-			// 字段类型
-			Typ:    fd.Type,
-			Offset: fd.Offset,
+		fieldMap[fdMeta.GoName] = fdMeta
+		columnMap[fdMeta.ColName] = fdMeta
+		if fdMeta.PrimaryKey {
+			pks = append(pks, fdMeta)
+		}
+		if fdMeta.Index != "" {
+			indexes = addIndex(indexes, fdMeta)
+		}
+		if fdMeta.SoftDelete {
+			if softDelete != nil {
+				return nil, errs.NewErrMultipleSoftDelete(softDelete.GoName, fdMeta.GoName)
+			}
+			softDelete = fdMeta
+		}
+		if fdMeta.Version {
+			if version != nil {
+				return nil, errs.NewErrMultipleVersion(version.GoName, fdMeta.GoName)
+			}
+			version = fdMeta
 		}
-		fieldMap[fd.Name] = fdMeta
-		columnMap[colName] = fdMeta
-		fields = append(fields, fdMeta)
 	}
 
 	var tableName string
@@ -143,6 +256,15 @@ func (r *registry) Register(entity any, opts ...Option) (*Model, error) {
 		FieldMap:  fieldMap,
 		ColumnMap: columnMap,
 		Fields:    fields,
+
+		PrimaryKeys:   pks,
+		Indexes:       indexes,
+		IgnoredFields: ignored,
+		SoftDelete:    softDelete,
+		Version:       version,
+	}
+	for _, rel := range relations {
+		res.addRelation(rel)
 	}
 	for _, opt := range opts {
 		err := opt(res)
@@ -150,6 +272,12 @@ func (r *registry) Register(entity any, opts ...Option) (*Model, error) {
 			return nil, err
 		}
 	}
+	// Option 可能会修改列或者声明关联关系，所以最后再处理外键
+	for _, rel := range res.Relations {
+		if err = res.initRelation(rel, elemType.Name()); err != nil {
+			return nil, err
+		}
+	}
 	r.models.Store(typ, res)
 	return res, nil
 }
@@ -175,8 +303,185 @@ func WithColumnName(field string, colName string) Option {
 	}
 }
 
-type User struct {
-	ID uint64 `orm::"column=id,xxxx=bbb"`
+// WithSharding 指定分片算法，分片键必须是模型的字段
+func WithSharding(alg sharding.Algorithm) Option {
+	return func(m *Model) error {
+		if _, ok := m.FieldMap[alg.ShardingKey()]; !ok {
+			return errs.NewErrUnknownField(alg.ShardingKey())
+		}
+		m.Sharding = alg
+		return nil
+	}
+}
+
+// parseFields 解析结构体的字段，匿名嵌入的结构体（包括指针）会被展开
+// offset 是 typ 相对于最内层指针指向的结构体（或者模型本身）的偏移量
+// ptrs 是访问 typ 需要经过的嵌入指针
+// 关联关系的字段不是列，放在第三个返回值里面
+func (r *registry) parseFields(typ reflect.Type, offset uintptr,
+	ptrs []EmbeddedPtr) ([]*Field, []string, []*Relation, error) {
+	numField := typ.NumField()
+	fields := make([]*Field, 0, numField)
+	var ignored []string
+	var relations []*Relation
+	for i := 0; i < numField; i++ {
+		fd := typ.Field(i)
+		if fd.Tag.Get("orm") == tagIgnore {
+			ignored = append(ignored, fd.Name)
+			continue
+		}
+		if isEmbeddedStruct(fd) {
+			subOffset := offset + fd.Offset
+			subTyp := fd.Type
+			subPtrs := ptrs
+			if fd.Type.Kind() == reflect.Ptr {
+				subTyp = fd.Type.Elem()
+				// 重新分配，避免不同的嵌入指针共用底层数组
+				subPtrs = make([]EmbeddedPtr, 0, len(ptrs)+1)
+				subPtrs = append(subPtrs, ptrs...)
+				subPtrs = append(subPtrs, EmbeddedPtr{Offset: subOffset, Typ: subTyp})
+				subOffset = 0
+			}
+			subFields, subIgnored, subRelations, err := r.parseFields(subTyp, subOffset, subPtrs)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			fields = append(fields, subFields...)
+			ignored = append(ignored, subIgnored...)
+			relations = append(relations, subRelations...)
+			continue
+		}
+		pair, err := r.parseTag(fd.Tag)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rel, err := parseRelation(fd, pair)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if rel != nil {
+			relations = append(relations, rel)
+			continue
+		}
+		fdMeta, err := r.parseField(fd, pair)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		fdMeta.Offset += offset
+		fdMeta.EmbeddedPtrs = ptrs
+		fields = append(fields, fdMeta)
+	}
+	return fields, ignored, relations, nil
+}
+
+// isEmbeddedStruct 判断是不是需要展开的匿名结构体
+// 实现了 sql.Scanner 的结构体（例如 sql.NullString）被当作一个列
+func isEmbeddedStruct(fd reflect.StructField) bool {
+	if !fd.Anonymous {
+		return false
+	}
+	typ := fd.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	return !reflect.PointerTo(typ).Implements(scannerType)
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// parseField 根据标签构造字段的元数据
+func (r *registry) parseField(fd reflect.StructField, pair map[string]string) (*Field, error) {
+	colName := pair[tagKeyColumn]
+	if colName == "" {
+		// 用户没有设置
+		colName = underscoreName(fd.Name)
+	}
+	res := &Field{
+		GoName:  fd.Name,
+		ColName: colName,
+		// 字段类型
+		Typ:    fd.Type,
+		Offset: fd.Offset,
+		Index:  pair[tagKeyIndex],
+	}
+	var err error
+	if res.PrimaryKey, err = parseBoolTag(pair, tagKeyPK); err != nil {
+		return nil, err
+	}
+	if res.AutoIncrement, err = parseBoolTag(pair, tagKeyAutoIncrement); err != nil {
+		return nil, err
+	}
+	if !res.AutoIncrement {
+		if res.AutoIncrement, err = parseBoolTag(pair, tagKeyAuto); err != nil {
+			return nil, err
+		}
+	}
+	if res.AutoIncrement && !isInteger(fd.Type) {
+		return nil, errs.NewErrInvalidAutoIncrementType(fd.Name, fd.Type)
+	}
+	if res.NotNull, err = parseBoolTag(pair, tagKeyNotNull); err != nil {
+		return nil, err
+	}
+	if res.Unique, err = parseBoolTag(pair, tagKeyUnique); err != nil {
+		return nil, err
+	}
+	if size, ok := pair[tagKeySize]; ok {
+		res.Size, err = strconv.Atoi(size)
+		if err != nil || res.Size <= 0 {
+			return nil, errs.NewErrInvalidTagContent(tagKeySize + "=" + size)
+		}
+	}
+	if res.SoftDelete, err = parseBoolTag(pair, tagKeySoftDelete); err != nil {
+		return nil, err
+	}
+	if res.SoftDelete && !isSoftDeleteType(fd.Type) {
+		return nil, errs.NewErrInvalidSoftDeleteType(fd.Name, fd.Type)
+	}
+	if res.AutoCreateTime, err = parseBoolTag(pair, tagKeyAutoCreateTime); err != nil {
+		return nil, err
+	}
+	if !res.AutoCreateTime {
+		if res.AutoCreateTime, err = parseBoolTag(pair, tagKeyAutoCreateTimeCamel); err != nil {
+			return nil, err
+		}
+	}
+	if res.AutoUpdateTime, err = parseBoolTag(pair, tagKeyAutoUpdateTime); err != nil {
+		return nil, err
+	}
+	if !res.AutoUpdateTime {
+		if res.AutoUpdateTime, err = parseBoolTag(pair, tagKeyAutoUpdateTimeCamel); err != nil {
+			return nil, err
+		}
+	}
+	if (res.AutoCreateTime || res.AutoUpdateTime) && !isTimeType(fd.Type) {
+		return nil, errs.NewErrInvalidAutoTimeType(fd.Name, fd.Type)
+	}
+	if res.Version, err = parseBoolTag(pair, tagKeyVersion); err != nil {
+		return nil, err
+	}
+	if res.Version && !isInteger(fd.Type) {
+		return nil, errs.NewErrInvalidVersionType(fd.Name, fd.Type)
+	}
+	if def, ok := pair[tagKeyDefault]; ok {
+		res.Default = &def
+	}
+	return res, nil
+}
+
+func addIndex(indexes []*Index, fd *Field) []*Index {
+	for _, idx := range indexes {
+		if idx.Name == fd.Index {
+			idx.Fields = append(idx.Fields, fd)
+			return indexes
+		}
+	}
+	return append(indexes, &Index{
+		Name:   fd.Index,
+		Fields: []*Field{fd},
+	})
 }
 
 func (r *registry) parseTag(tag reflect.StructTag) (map[string]string, error) {
@@ -187,8 +492,13 @@ func (r *registry) parseTag(tag reflect.StructTag) (map[string]string, error) {
 	paris := strings.Split(ormTag, ",")
 	res := make(map[string]string, len(paris))
 	for _, pair := range paris {
-		segs := strings.Split(pair, "=")
-		if len(segs) != 2 {
+		segs := strings.SplitN(pair, "=", 2)
+		if len(segs) == 1 {
+			// orm:"pk" 等价于 orm:"pk=true"
+			if _, ok := boolTagKeys[pair]; ok {
+				res[pair] = "true"
+				continue
+			}
 			return nil, errs.NewErrInvalidTagContent(pair)
 		}
 		key := segs[0]
@@ -198,6 +508,46 @@ func (r *registry) parseTag(tag reflect.StructTag) (map[string]string, error) {
 	return res, nil
 }
 
+// parseBoolTag 解析 pk=true 之类的布尔标签，没有设置就是 false
+func parseBoolTag(pair map[string]string, key string) (bool, error) {
+	val, ok := pair[key]
+	if !ok {
+		return false, nil
+	}
+	res, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, errs.NewErrInvalidTagContent(key + "=" + val)
+	}
+	return res, nil
+}
+
+// isSoftDeleteType 软删除用 NULL 代表没有删除，删除的时候写入时间或者毫秒时间戳
+func isSoftDeleteType(typ reflect.Type) bool {
+	return isTimeType(typ) && (typ.Kind() == reflect.Ptr || reflect.PointerTo(typ).Implements(scannerType))
+}
+
+// isTimeType 可以写入时间的类型，整数代表毫秒时间戳
+func isTimeType(typ reflect.Type) bool {
+	switch typ {
+	case reflect.TypeOf(sql.NullTime{}), reflect.TypeOf(sql.NullInt64{}):
+		return true
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ == reflect.TypeOf(time.Time{}) || isInteger(typ)
+}
+
+func isInteger(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
 // underscoreName 驼峰转字符串命名
 func underscoreName(tableName string) string {
 	var buf []byte
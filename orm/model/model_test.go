@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"reflect"
 	"scaffolding-go/orm/internal/errs"
+	"scaffolding-go/orm/sharding"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -200,6 +202,328 @@ func TestRegister_get(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "primary key",
+			entity: func() any {
+				type PKTable struct {
+					Id        uint64 `orm:"pk=true,auto=true"`
+					FirstName string `orm:"pk=false"`
+				}
+				return &PKTable{}
+			}(),
+			wantModel: &Model{
+				TableName: "p_k_table",
+				Fields: []*Field{
+					{
+						ColName:       "id",
+						GoName:        "Id",
+						Typ:           reflect.TypeOf(uint64(0)),
+						PrimaryKey:    true,
+						AutoIncrement: true,
+					},
+					{
+						ColName: "first_name",
+						GoName:  "FirstName",
+						Typ:     reflect.TypeOf(""),
+						Offset:  8,
+					},
+				},
+				PrimaryKeys: []*Field{
+					{
+						ColName:       "id",
+						GoName:        "Id",
+						Typ:           reflect.TypeOf(uint64(0)),
+						PrimaryKey:    true,
+						AutoIncrement: true,
+					},
+				},
+			},
+		},
+		{
+			name: "column options",
+			entity: func() any {
+				type OptionTable struct {
+					Id        int64  `orm:"pk,auto_increment"`
+					TenantId  int64  `orm:"pk,index=idx_tenant_name"`
+					FirstName string `orm:"size=64,not_null,default=,index=idx_tenant_name"`
+					Email     string `orm:"unique=true,size=128"`
+					Age       int8   `orm:"default=18"`
+					Cache     string `orm:"-"`
+				}
+				return &OptionTable{}
+			}(),
+			wantModel: func() *Model {
+				empty, age := "", "18"
+				id := &Field{ColName: "id", GoName: "Id", Typ: reflect.TypeOf(int64(0)),
+					PrimaryKey: true, AutoIncrement: true}
+				tenant := &Field{ColName: "tenant_id", GoName: "TenantId", Typ: reflect.TypeOf(int64(0)),
+					Offset: 8, PrimaryKey: true, Index: "idx_tenant_name"}
+				name := &Field{ColName: "first_name", GoName: "FirstName", Typ: reflect.TypeOf(""),
+					Offset: 16, Size: 64, NotNull: true, Default: &empty, Index: "idx_tenant_name"}
+				return &Model{
+					TableName: "option_table",
+					Fields: []*Field{
+						id, tenant, name,
+						{ColName: "email", GoName: "Email", Typ: reflect.TypeOf(""),
+							Offset: 32, Size: 128, Unique: true},
+						{ColName: "age", GoName: "Age", Typ: reflect.TypeOf(int8(0)),
+							Offset: 48, Default: &age},
+					},
+					PrimaryKeys: []*Field{id, tenant},
+					Indexes: []*Index{
+						{Name: "idx_tenant_name", Fields: []*Field{tenant, name}},
+					},
+					IgnoredFields: []string{"Cache"},
+				}
+			}(),
+		},
+		{
+			name: "invalid size",
+			entity: func() any {
+				type OptionTable struct {
+					FirstName string `orm:"size=abc"`
+				}
+				return &OptionTable{}
+			}(),
+			wantErr: errs.NewErrInvalidTagContent("size=abc"),
+		},
+		{
+			name: "invalid pk",
+			entity: func() any {
+				type PKTable struct {
+					Id int64 `orm:"pk=abc"`
+				}
+				return &PKTable{}
+			}(),
+			wantErr: errs.NewErrInvalidTagContent("pk=abc"),
+		},
+		{
+			name: "auto not integer",
+			entity: func() any {
+				type PKTable struct {
+					Id string `orm:"pk=true,auto=true"`
+				}
+				return &PKTable{}
+			}(),
+			wantErr: errs.NewErrInvalidAutoIncrementType("Id", reflect.TypeOf("")),
+		},
+		{
+			name: "soft delete",
+			entity: func() any {
+				type SoftTable struct {
+					Id        int64      `orm:"pk"`
+					DeletedAt *time.Time `orm:"soft_delete"`
+				}
+				return &SoftTable{}
+			}(),
+			wantModel: func() *Model {
+				id := &Field{ColName: "id", GoName: "Id", Typ: reflect.TypeOf(int64(0)), PrimaryKey: true}
+				deleted := &Field{ColName: "deleted_at", GoName: "DeletedAt", Typ: reflect.TypeOf(&time.Time{}),
+					Offset: 8, SoftDelete: true}
+				return &Model{
+					TableName:   "soft_table",
+					Fields:      []*Field{id, deleted},
+					PrimaryKeys: []*Field{id},
+					SoftDelete:  deleted,
+				}
+			}(),
+		},
+		{
+			name: "soft delete not nullable",
+			entity: func() any {
+				type SoftTable struct {
+					DeletedAt time.Time `orm:"soft_delete"`
+				}
+				return &SoftTable{}
+			}(),
+			wantErr: errs.NewErrInvalidSoftDeleteType("DeletedAt", reflect.TypeOf(time.Time{})),
+		},
+		{
+			name: "multiple soft delete",
+			entity: func() any {
+				type SoftTable struct {
+					DeletedAt sql.NullTime  `orm:"soft_delete"`
+					RemovedAt sql.NullInt64 `orm:"soft_delete"`
+				}
+				return &SoftTable{}
+			}(),
+			wantErr: errs.NewErrMultipleSoftDelete("DeletedAt", "RemovedAt"),
+		},
+		{
+			name: "auto time",
+			entity: func() any {
+				type TimeTable struct {
+					CreatedAt time.Time `orm:"auto_create_time"`
+					UpdatedAt int64     `orm:"autoUpdateTime"`
+				}
+				return &TimeTable{}
+			}(),
+			wantModel: &Model{
+				TableName: "time_table",
+				Fields: []*Field{
+					{ColName: "created_at", GoName: "CreatedAt", Typ: reflect.TypeOf(time.Time{}),
+						AutoCreateTime: true},
+					{ColName: "updated_at", GoName: "UpdatedAt", Typ: reflect.TypeOf(int64(0)),
+						Offset: 24, AutoUpdateTime: true},
+				},
+			},
+		},
+		{
+			name: "auto time invalid type",
+			entity: func() any {
+				type TimeTable struct {
+					CreatedAt string `orm:"auto_create_time"`
+				}
+				return &TimeTable{}
+			}(),
+			wantErr: errs.NewErrInvalidAutoTimeType("CreatedAt", reflect.TypeOf("")),
+		},
+		{
+			name: "version",
+			entity: func() any {
+				type VersionTable struct {
+					Version uint32 `orm:"version"`
+				}
+				return &VersionTable{}
+			}(),
+			wantModel: func() *Model {
+				version := &Field{ColName: "version", GoName: "Version", Typ: reflect.TypeOf(uint32(0)),
+					Version: true}
+				return &Model{
+					TableName: "version_table",
+					Fields:    []*Field{version},
+					Version:   version,
+				}
+			}(),
+		},
+		{
+			name: "version not integer",
+			entity: func() any {
+				type VersionTable struct {
+					Version string `orm:"version"`
+				}
+				return &VersionTable{}
+			}(),
+			wantErr: errs.NewErrInvalidVersionType("Version", reflect.TypeOf("")),
+		},
+		{
+			name:   "relations",
+			entity: &RelUser{},
+			wantModel: func() *Model {
+				id := &Field{ColName: "id", GoName: "Id", Typ: reflect.TypeOf(int64(0)), PrimaryKey: true}
+				inviterId := &Field{ColName: "inviter_id", GoName: "InviterId", Typ: reflect.TypeOf(int64(0)), Offset: 8}
+				return &Model{
+					TableName:   "rel_user",
+					Fields:      []*Field{id, inviterId},
+					PrimaryKeys: []*Field{id},
+					Relations: map[string]*Relation{
+						"Profile": {Name: "Profile", Kind: RelationHasOne, Typ: reflect.TypeOf(&RelProfile{}),
+							Target: reflect.TypeOf(RelProfile{}), ForeignKey: "RelUserId"},
+						"Orders": {Name: "Orders", Kind: RelationHasMany, Typ: reflect.TypeOf([]RelOrder{}),
+							Target: reflect.TypeOf(RelOrder{}), ForeignKey: "BuyerId", References: "Id"},
+						"Inviter": {Name: "Inviter", Kind: RelationBelongsTo, Typ: reflect.TypeOf(&RelUser{}),
+							Target: reflect.TypeOf(RelUser{}), ForeignKey: "InviterId"},
+					},
+				}
+			}(),
+		},
+		{
+			name: "invalid relation type",
+			entity: func() any {
+				type RelTable struct {
+					Profile RelProfile `orm:"has_one"`
+				}
+				return &RelTable{}
+			}(),
+			wantErr: errs.NewErrInvalidRelationType("Profile", reflect.TypeOf(RelProfile{})),
+		},
+		{
+			name: "invalid foreign key",
+			entity: func() any {
+				type RelTable struct {
+					Id     int64
+					Orders []RelOrder `orm:"has_many"`
+				}
+				return &RelTable{}
+			}(),
+			wantErr: errs.NewErrInvalidForeignKey("Orders", "RelTableId"),
+		},
+		{
+			name:   "embedded",
+			entity: &EmbedModel{},
+			wantModel: func() *Model {
+				id := &Field{ColName: "id", GoName: "Id", Typ: reflect.TypeOf(int64(0)),
+					PrimaryKey: true, AutoIncrement: true}
+				ptrs := []EmbeddedPtr{{Offset: 32, Typ: reflect.TypeOf(Deleted{})}}
+				return &Model{
+					TableName: "embed_model",
+					Fields: []*Field{
+						id,
+						{ColName: "created_at", GoName: "CreatedAt", Typ: reflect.TypeOf(int64(0)), Offset: 8},
+						{ColName: "name", GoName: "Name", Typ: reflect.TypeOf(""), Offset: 16},
+						{ColName: "deleted_at", GoName: "DeletedAt", Typ: reflect.TypeOf(int64(0)),
+							EmbeddedPtrs: ptrs},
+						{ColName: "deleted_by", GoName: "DeletedBy", Typ: reflect.TypeOf(int64(0)),
+							Offset: 8, EmbeddedPtrs: ptrs},
+					},
+					PrimaryKeys: []*Field{id},
+				}
+			}(),
+		},
+		{
+			name: "embedded scanner",
+			entity: func() any {
+				type ScannerModel struct {
+					sql.NullString
+				}
+				return &ScannerModel{}
+			}(),
+			wantModel: &Model{
+				TableName: "scanner_model",
+				Fields: []*Field{
+					{ColName: "null_string", GoName: "NullString", Typ: reflect.TypeOf(sql.NullString{})},
+				},
+			},
+		},
+		{
+			name: "ignore embedded",
+			entity: func() any {
+				type IgnoreEmbedModel struct {
+					BaseModel `orm:"-"`
+					Name      string
+				}
+				return &IgnoreEmbedModel{}
+			}(),
+			wantModel: &Model{
+				TableName: "ignore_embed_model",
+				Fields: []*Field{
+					{ColName: "name", GoName: "Name", Typ: reflect.TypeOf(""), Offset: 16},
+				},
+				IgnoredFields: []string{"BaseModel"},
+			},
+		},
+		{
+			name: "duplicate column",
+			entity: func() any {
+				type DupModel struct {
+					BaseModel
+					Created int64 `orm:"column=created_at"`
+				}
+				return &DupModel{}
+			}(),
+			wantErr: errs.NewErrDuplicateColumn("created_at", "CreatedAt", "Created"),
+		},
+		{
+			name: "duplicate field",
+			entity: func() any {
+				type DupModel struct {
+					BaseModel
+					Id int64 `orm:"column=dup_id"`
+				}
+				return &DupModel{}
+			}(),
+			wantErr: errs.NewErrDuplicateField("Id", "id", "dup_id"),
+		},
 		{
 			name:   "table name",
 			entity: &CustomTableName{},
@@ -270,6 +594,22 @@ func TestRegister_get(t *testing.T) {
 	}
 }
 
+type BaseModel struct {
+	Id        int64 `orm:"pk,auto_increment"`
+	CreatedAt int64
+}
+
+type Deleted struct {
+	DeletedAt int64
+	DeletedBy int64
+}
+
+type EmbedModel struct {
+	BaseModel
+	Name string
+	*Deleted
+}
+
 type CustomTableName struct {
 	FirstName string
 }
@@ -333,6 +673,97 @@ func TestModelWithTableName(t *testing.T) {
 	assert.Equal(t, "test_model_ttt", m.TableName)
 }
 
+type RelUser struct {
+	Id        int64 `orm:"pk"`
+	InviterId int64
+	Profile   *RelProfile `orm:"has_one"`
+	Orders    []RelOrder  `orm:"has_many,foreign_key=BuyerId,references=Id"`
+	Inviter   *RelUser    `orm:"belongs_to"`
+}
+
+type RelProfile struct {
+	RelUserId int64
+}
+
+type RelOrder struct {
+	BuyerId int64
+}
+
+func TestModelWithRelation(t *testing.T) {
+	type OptionUser struct {
+		Id        int64
+		Orders    []*RelOrder
+		Name      string
+		Inviter   *OptionUser
+		InviterId int64
+	}
+	testCases := []struct {
+		name    string
+		opt     Option
+		wantRel *Relation
+		wantErr error
+	}{
+		{
+			name: "has many",
+			opt:  WithHasMany("Orders", "BuyerId"),
+			wantRel: &Relation{Name: "Orders", Kind: RelationHasMany, Typ: reflect.TypeOf([]*RelOrder{}),
+				Target: reflect.TypeOf(RelOrder{}), ForeignKey: "BuyerId"},
+		},
+		{
+			name: "belongs to default foreign key",
+			opt:  WithBelongsTo("Inviter", ""),
+			wantRel: &Relation{Name: "Inviter", Kind: RelationBelongsTo, Typ: reflect.TypeOf(&OptionUser{}),
+				Target: reflect.TypeOf(OptionUser{}), ForeignKey: "InviterId"},
+		},
+		{
+			name:    "has many default foreign key",
+			opt:     WithHasMany("Orders", ""),
+			wantErr: errs.NewErrInvalidForeignKey("Orders", "OptionUserId"),
+		},
+		{
+			name:    "belongs to unknown foreign key",
+			opt:     WithBelongsTo("Inviter", "CreatorId"),
+			wantErr: errs.NewErrInvalidForeignKey("Inviter", "CreatorId"),
+		},
+		{
+			name:    "invalid type",
+			opt:     WithHasOne("Orders", "BuyerId"),
+			wantErr: errs.NewErrInvalidRelationType("Orders", reflect.TypeOf([]*RelOrder{})),
+		},
+		{
+			name:    "unknown field",
+			opt:     WithBelongsTo("Invalid", "InvalidId"),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewRegistry().Register(&OptionUser{}, tc.opt)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRel, m.Relations[tc.wantRel.Name])
+			// 关联关系不再是列
+			_, ok := m.FieldMap[tc.wantRel.Name]
+			assert.False(t, ok)
+			_, ok = m.ColumnMap[underscoreName(tc.wantRel.Name)]
+			assert.False(t, ok)
+			assert.Len(t, m.Fields, 4)
+		})
+	}
+}
+
+func TestModelWithSharding(t *testing.T) {
+	alg := &sharding.Hash{Key: "Id", TablePattern: "test_model_%d", TableCount: 4}
+	m, err := NewRegistry().Register(&TestModel{}, WithSharding(alg))
+	require.NoError(t, err)
+	assert.Equal(t, alg, m.Sharding)
+
+	_, err = NewRegistry().Register(&TestModel{}, WithSharding(&sharding.Hash{Key: "Invalid"}))
+	assert.Equal(t, errs.NewErrUnknownField("Invalid"), err)
+}
+
 func TestModelWithColumnName(t *testing.T) {
 	testCases := []struct {
 		name    string
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"reflect"
+	"scaffolding-go/orm/internal/errs"
+)
+
+const (
+	// tagKeyHasOne 例如 User 上的 Profile *Profile `orm:"has_one"`，Profile 上有 UserId
+	tagKeyHasOne = "has_one"
+	// tagKeyHasMany 例如 User 上的 Orders []*Order `orm:"has_many"`，Order 上有 UserId
+	tagKeyHasMany = "has_many"
+	// tagKeyBelongsTo 例如 Order 上的 User *User `orm:"belongs_to"`，Order 上有 UserId
+	tagKeyBelongsTo = "belongs_to"
+	// tagKeyForeignKey 指定外键的字段名，例如 orm:"has_many,foreign_key=BuyerId"
+	tagKeyForeignKey = "foreign_key"
+	// tagKeyReferences 指定外键引用的字段名，默认是主键
+	tagKeyReferences = "references"
+)
+
+type RelationKind uint8
+
+const (
+	RelationHasOne RelationKind = iota + 1
+	RelationHasMany
+	RelationBelongsTo
+)
+
+// Relation 代表一个关联关系，关联关系的字段不会作为列
+type Relation struct {
+	// 字段名，例如 Orders
+	Name string
+	Kind RelationKind
+	// 字段的类型，例如 []*Order
+	Typ reflect.Type
+	// 关联的结构体类型，例如 Order
+	Target reflect.Type
+	// 外键的字段名
+	// has_one 和 has_many 在关联的结构体上，默认是 模型名+Id
+	// belongs_to 在模型自己身上，默认是 字段名+Id
+	ForeignKey string
+	// 外键引用的字段名，空字符串代表主键
+	// has_one 和 has_many 在模型自己身上，belongs_to 在关联的结构体上
+	References string
+}
+
+// parseRelation 字段没有声明关联关系的时候返回 nil
+func parseRelation(fd reflect.StructField, pair map[string]string) (*Relation, error) {
+	var kind RelationKind
+	for k, key := range []string{RelationHasOne: tagKeyHasOne,
+		RelationHasMany: tagKeyHasMany, RelationBelongsTo: tagKeyBelongsTo} {
+		ok, err := parseBoolTag(pair, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			kind = RelationKind(k)
+			break
+		}
+	}
+	if kind == 0 {
+		return nil, nil
+	}
+	return newRelation(fd.Name, fd.Type, kind, pair[tagKeyForeignKey], pair[tagKeyReferences])
+}
+
+func newRelation(name string, typ reflect.Type, kind RelationKind,
+	foreignKey string, references string) (*Relation, error) {
+	target := typ
+	if kind == RelationHasMany {
+		if target.Kind() != reflect.Slice {
+			return nil, errs.NewErrInvalidRelationType(name, typ)
+		}
+		target = target.Elem()
+	}
+	if target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	} else if kind != RelationHasMany {
+		// has_one 和 belongs_to 用 nil 代表没有关联的数据
+		return nil, errs.NewErrInvalidRelationType(name, typ)
+	}
+	if target.Kind() != reflect.Struct {
+		return nil, errs.NewErrInvalidRelationType(name, typ)
+	}
+	return &Relation{
+		Name:       name,
+		Kind:       kind,
+		Typ:        typ,
+		Target:     target,
+		ForeignKey: foreignKey,
+		References: references,
+	}, nil
+}
+
+// WithHasOne 声明 field 是 has_one 关联，foreignKey 是关联的结构体上的字段名
+// foreignKey 是空字符串的时候和标签一样，默认是 模型名+Id
+func WithHasOne(field string, foreignKey string) Option {
+	return withRelation(field, RelationHasOne, foreignKey)
+}
+
+// WithHasMany 声明 field 是 has_many 关联，foreignKey 是关联的结构体上的字段名
+// foreignKey 是空字符串的时候和标签一样，默认是 模型名+Id
+func WithHasMany(field string, foreignKey string) Option {
+	return withRelation(field, RelationHasMany, foreignKey)
+}
+
+// WithBelongsTo 声明 field 是 belongs_to 关联，foreignKey 是模型自己的字段名
+// foreignKey 是空字符串的时候和标签一样，默认是 字段名+Id
+func WithBelongsTo(field string, foreignKey string) Option {
+	return withRelation(field, RelationBelongsTo, foreignKey)
+}
+
+func withRelation(field string, kind RelationKind, foreignKey string) Option {
+	return func(m *Model) error {
+		fd, ok := m.FieldMap[field]
+		if !ok {
+			return errs.NewErrUnknownField(field)
+		}
+		rel, err := newRelation(field, fd.Typ, kind, foreignKey, "")
+		if err != nil {
+			return err
+		}
+		// 之前被当成了列，这里去掉
+		delete(m.FieldMap, fd.GoName)
+		delete(m.ColumnMap, fd.ColName)
+		fields := make([]*Field, 0, len(m.Fields)-1)
+		for _, f := range m.Fields {
+			if f != fd {
+				fields = append(fields, f)
+			}
+		}
+		m.Fields = fields
+		m.addRelation(rel)
+		return nil
+	}
+}
+
+// initRelation 补全默认的外键，并且检查外键的字段是否存在
+// 标签和 Option 声明的关联关系都在这里处理，modelName 是模型的结构体名字
+func (m *Model) initRelation(rel *Relation, modelName string) error {
+	if rel.ForeignKey == "" {
+		if rel.Kind == RelationBelongsTo {
+			rel.ForeignKey = rel.Name + "Id"
+		} else {
+			rel.ForeignKey = modelName + "Id"
+		}
+	}
+	var ok bool
+	if rel.Kind == RelationBelongsTo {
+		_, ok = m.FieldMap[rel.ForeignKey]
+	} else {
+		_, ok = rel.Target.FieldByName(rel.ForeignKey)
+	}
+	if !ok {
+		return errs.NewErrInvalidForeignKey(rel.Name, rel.ForeignKey)
+	}
+	return nil
+}
+
+func (m *Model) addRelation(rel *Relation) {
+	if m.Relations == nil {
+		m.Relations = make(map[string]*Relation, 2)
+	}
+	m.Relations[rel.Name] = rel
+}
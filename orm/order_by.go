@@ -0,0 +1,23 @@
+package orm
+
+// OrderBy 代表排序
+type OrderBy struct {
+	col   string
+	order string
+}
+
+// Asc 升序
+func Asc(col string) OrderBy {
+	return OrderBy{
+		col:   col,
+		order: "ASC",
+	}
+}
+
+// Desc 降序
+func Desc(col string) OrderBy {
+	return OrderBy{
+		col:   col,
+		order: "DESC",
+	}
+}
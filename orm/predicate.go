@@ -4,11 +4,29 @@ package orm
 type op string
 
 const (
-	opEq  op = "="
-	opLT  op = "<"
-	opNot op = "NOT"
-	opAnd op = "AND"
-	opOr  op = "OR"
+	opEq        op = "="
+	opNEQ       op = "!="
+	opLT        op = "<"
+	opLTEQ      op = "<="
+	opGT        op = ">"
+	opGTEQ      op = ">="
+	opLike      op = "LIKE"
+	opNotLike   op = "NOT LIKE"
+	opIn        op = "IN"
+	opNotIn     op = "NOT IN"
+	opBetween   op = "BETWEEN"
+	opIsNull    op = "IS NULL"
+	opIsNotNull op = "IS NOT NULL"
+	opExists    op = "EXISTS"
+	opNotExists op = "NOT EXISTS"
+	opNot       op = "NOT"
+	opAnd       op = "AND"
+	opOr        op = "OR"
+
+	opAdd   op = "+"
+	opSub   op = "-"
+	opMulti op = "*"
+	opDiv   op = "/"
 )
 
 func (o op) String() string {
@@ -74,3 +92,18 @@ type value struct {
 func (value) expr() {
 
 }
+
+// valueList 代表 IN 后面的参数列表
+type valueList struct {
+	vals []any
+}
+
+func (valueList) expr() {}
+
+// betweenExpr 代表 BETWEEN 后面的 start AND end
+type betweenExpr struct {
+	start Expression
+	end   Expression
+}
+
+func (betweenExpr) expr() {}
@@ -0,0 +1,236 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"scaffolding-go/orm/internal/errs"
+	"scaffolding-go/orm/internal/valuer"
+	"scaffolding-go/orm/model"
+)
+
+// preloadBuilder 查询关联的数据
+// SELECT * FROM target WHERE col IN (...)
+type preloadBuilder struct {
+	builder
+	col  string
+	keys []any
+}
+
+func (p *preloadBuilder) readOnly() {}
+
+func (p *preloadBuilder) Build() (*Query, error) {
+	p.reset()
+	p.sb.WriteString("SELECT * FROM ")
+	p.quote(p.model.TableName)
+	p.sb.WriteString(" WHERE ")
+	pred := C(p.col).In(p.keys...)
+	if p.model.SoftDelete != nil {
+		pred = pred.And(C(p.model.SoftDelete.GoName).IsNull())
+	}
+	if err := p.buildExpression(pred); err != nil {
+		return nil, err
+	}
+	p.sb.WriteByte(';')
+	return &Query{
+		SQL:  p.sb.String(),
+		Args: p.args,
+	}, nil
+}
+
+// preload 加载 owners 的关联关系，每个关联关系只发起一次 IN 查询
+// c.model 是 T 的元数据
+func preload[T any](ctx context.Context, sess Session, c core, owners []*T, names []string) error {
+	if len(owners) == 0 {
+		return nil
+	}
+	vals := make([]reflect.Value, 0, len(owners))
+	for _, owner := range owners {
+		vals = append(vals, reflect.ValueOf(owner).Elem())
+	}
+	for _, name := range names {
+		rel, ok := c.model.Relations[name]
+		if !ok {
+			return errs.NewErrUnknownRelation(name)
+		}
+		if err := preloadRelation(ctx, sess, c, rel, vals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func preloadRelation(ctx context.Context, sess Session, c core,
+	rel *model.Relation, owners []reflect.Value) error {
+	target, err := c.r.Get(reflect.New(rel.Target).Interface())
+	if err != nil {
+		return err
+	}
+	// ownerKey 是模型上用来关联的字段，targetKey 是关联的结构体上的
+	var ownerKey, targetKey string
+	if rel.Kind == model.RelationBelongsTo {
+		ownerKey = rel.ForeignKey
+		if targetKey, err = relationReferences(rel, target); err != nil {
+			return err
+		}
+	} else {
+		targetKey = rel.ForeignKey
+		if ownerKey, err = relationReferences(rel, c.model); err != nil {
+			return err
+		}
+	}
+	if _, ok := c.model.FieldMap[ownerKey]; !ok {
+		return errs.NewErrUnknownField(ownerKey)
+	}
+
+	keys := make([]any, 0, len(owners))
+	seen := make(map[any]struct{}, len(owners))
+	for _, owner := range owners {
+		key, ok := relationKey(owner, ownerKey)
+		if !ok {
+			continue
+		}
+		if _, ok = seen[key]; !ok {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+
+	matches := make(map[any][]reflect.Value, len(keys))
+	if len(keys) > 0 {
+		c.model = target
+		res := queryRelation(ctx, sess, c, rel.Target, &QueryContext{
+			Type: "SELECT",
+			Builder: &preloadBuilder{
+				builder: builder{
+					core:   c,
+					quoter: c.dialect.quoter(),
+				},
+				col:  targetKey,
+				keys: keys,
+			},
+			Model: target,
+		})
+		if res.Err != nil {
+			return res.Err
+		}
+		for _, t := range res.Result.([]reflect.Value) {
+			if key, ok := relationKey(t.Elem(), targetKey); ok {
+				matches[key] = append(matches[key], t)
+			}
+		}
+	}
+
+	for _, owner := range owners {
+		key, _ := relationKey(owner, ownerKey)
+		fd, _ := valuer.FieldByName(owner, rel.Name, true)
+		if rel.Kind == model.RelationHasMany {
+			// 没有关联数据的时候是空切片，和没有加载区分开
+			slice := reflect.MakeSlice(rel.Typ, 0, len(matches[key]))
+			for _, m := range matches[key] {
+				if rel.Typ.Elem().Kind() != reflect.Ptr {
+					m = m.Elem()
+				}
+				slice = reflect.Append(slice, m)
+			}
+			fd.Set(slice)
+			continue
+		}
+		if ms := matches[key]; len(ms) > 0 {
+			fd.Set(ms[0])
+		}
+	}
+	return nil
+}
+
+// relationReferences 外键引用的字段，没有指定的时候是主键
+func relationReferences(rel *model.Relation, m *model.Model) (string, error) {
+	if rel.References != "" {
+		return rel.References, nil
+	}
+	if len(m.PrimaryKeys) != 1 {
+		return "", errs.NewErrRelationWithoutReferences(rel.Name)
+	}
+	return m.PrimaryKeys[0].GoName, nil
+}
+
+// relationKey 读取关联字段的值，统一成可以比较的类型
+// 外键可能是指针或者 sql.NullInt64 之类的，NULL 返回 false
+func relationKey(val reflect.Value, name string) (any, bool) {
+	fd, ok := valuer.FieldByName(val, name, false)
+	if !ok {
+		return nil, false
+	}
+	if fd.Kind() == reflect.Ptr {
+		if fd.IsNil() {
+			return nil, false
+		}
+		fd = fd.Elem()
+	}
+	if v, ok := fd.Interface().(driver.Valuer); ok {
+		dv, err := v.Value()
+		if err != nil || dv == nil {
+			return nil, false
+		}
+		fd = reflect.ValueOf(dv)
+	}
+	switch {
+	case fd.CanInt():
+		return fd.Int(), true
+	case fd.CanUint():
+		return int64(fd.Uint()), true
+	default:
+		return fd.Interface(), true
+	}
+}
+
+// queryRelation 和 getMulti 类似，但是类型是运行时才知道的
+// QueryResult.Result 是 []reflect.Value，每一个都是指向 typ 的指针
+func queryRelation(ctx context.Context, sess Session, c core,
+	typ reflect.Type, qc *QueryContext) *QueryResult {
+	qc.Tx = sessionTx(ctx, sess)
+	var root Handler = func(ctx context.Context, qc *QueryContext) *QueryResult {
+		q, err := qc.Builder.Build()
+		if err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+		rows, err := query(ctx, sess, qc, q)
+		if err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+		defer func() {
+			_ = rows.Close()
+		}()
+		res := make([]reflect.Value, 0, 8)
+		for rows.Next() {
+			tp := reflect.New(typ)
+			if err = c.creator(qc.Model, tp.Interface()).SetColumns(rows); err != nil {
+				return &QueryResult{
+					Err: err,
+				}
+			}
+			if err = afterFind(ctx, tp.Interface()); err != nil {
+				return &QueryResult{
+					Err: err,
+				}
+			}
+			res = append(res, tp)
+		}
+		if err = rows.Err(); err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+		return &QueryResult{
+			Result: res,
+		}
+	}
+	for i := len(c.mdls) - 1; i >= 0; i-- {
+		root = c.mdls[i](root)
+	}
+	return root(ctx, qc)
+}
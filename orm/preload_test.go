@@ -0,0 +1,107 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"scaffolding-go/orm/internal/errs"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type PreloadUser struct {
+	Id      int64 `orm:"pk"`
+	Name    string
+	Profile *PreloadProfile `orm:"has_one,foreign_key=UserId"`
+	Orders  []PreloadOrder  `orm:"has_many,foreign_key=UserId"`
+}
+
+type PreloadProfile struct {
+	Id     int64 `orm:"pk"`
+	UserId int64
+	Bio    string
+}
+
+type PreloadOrder struct {
+	Id     int64 `orm:"pk"`
+	UserId sql.NullInt64
+	User   *PreloadUser `orm:"belongs_to"`
+}
+
+func TestSelector_Preload(t *testing.T) {
+	db, err := Open("sqlite3", "file:preload.db?cache=shared&mode=memory",
+		DBWithDialect(DialectSQLite))
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = NewMigrator(db).AutoMigrate(ctx, &PreloadUser{}, &PreloadProfile{}, &PreloadOrder{})
+	require.NoError(t, err)
+	require.NoError(t, NewInserter[PreloadUser](db).Values(
+		&PreloadUser{Id: 1, Name: "Tom"}, &PreloadUser{Id: 2, Name: "Jerry"}).Exec(ctx).Err())
+	require.NoError(t, NewInserter[PreloadProfile](db).Values(
+		&PreloadProfile{Id: 1, UserId: 1, Bio: "cat"}).Exec(ctx).Err())
+	require.NoError(t, NewInserter[PreloadOrder](db).Values(
+		&PreloadOrder{Id: 1, UserId: sql.NullInt64{Int64: 1, Valid: true}},
+		&PreloadOrder{Id: 2, UserId: sql.NullInt64{Int64: 1, Valid: true}},
+		&PreloadOrder{Id: 3}).Exec(ctx).Err())
+
+	users, err := NewSelector[PreloadUser](db).Preload("Profile", "Orders").GetMulti(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, &PreloadProfile{Id: 1, UserId: 1, Bio: "cat"}, users[0].Profile)
+	assert.Equal(t, []int64{1, 2}, []int64{users[0].Orders[0].Id, users[0].Orders[1].Id})
+	assert.Nil(t, users[1].Profile)
+	assert.Equal(t, []PreloadOrder{}, users[1].Orders)
+
+	orders, err := NewSelector[PreloadOrder](db).Preload("User").GetMulti(ctx)
+	require.NoError(t, err)
+	require.Len(t, orders, 3)
+	assert.Equal(t, "Tom", orders[0].User.Name)
+	assert.Same(t, orders[0].User, orders[1].User)
+	// 外键是 NULL
+	assert.Nil(t, orders[2].User)
+
+	order, err := NewSelector[PreloadOrder](db).Where(C("Id").Eq(1)).Preload("User").Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Tom", order.User.Name)
+}
+
+func TestSelector_PreloadQuery(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `preload_user`;")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom").AddRow(2, "Jerry"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `preload_order` WHERE `user_id` IN (?,?);")).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 2))
+	users, err := NewSelector[PreloadUser](db).Preload("Orders").GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, users[0].Orders)
+	assert.Equal(t, []PreloadOrder{{Id: 1, UserId: sql.NullInt64{Int64: 2, Valid: true}}}, users[1].Orders)
+
+	// 相同的外键只查询一次
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `preload_order`;")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 2).AddRow(2, 2))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `preload_user` WHERE `id` IN (?);")).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "Jerry"))
+	orders, err := NewSelector[PreloadOrder](db).Preload("User").GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Jerry", orders[1].User.Name)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `preload_user`;")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Tom"))
+	_, err = NewSelector[PreloadUser](db).Preload("Invalid").GetMulti(ctx)
+	assert.Equal(t, errs.NewErrUnknownRelation("Invalid"), err)
+
+	for _, err = range NewSelector[PreloadUser](db).Preload("Orders").Iter(ctx) {
+		assert.Equal(t, errs.ErrPreloadWithIter, err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
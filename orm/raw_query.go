@@ -3,6 +3,7 @@ package orm
 import (
 	"context"
 	"database/sql"
+	"iter"
 )
 
 type RawQuerier[T any] struct {
@@ -10,15 +11,29 @@ type RawQuerier[T any] struct {
 	sess Session
 	sql  string
 	args []any
+	// 不为 nil 的时候，SQL 由它构造
+	qb QueryBuilder
 }
 
 func (r *RawQuerier[T]) Build() (*Query, error) {
+	if r.qb != nil {
+		return r.qb.Build()
+	}
 	return &Query{
 		SQL:  r.sql,
 		Args: r.args,
 	}, nil
 }
 
+// queryType 组合查询之类只读的 QueryBuilder 和 Selector 一样，是 SELECT 或者 ITER
+// 其余的原生 SQL 不一定是 SELECT，类型是 RAW，这样 safedml 之类的中间件才会检查
+func (r *RawQuerier[T]) queryType(typ string) string {
+	if _, ok := r.qb.(readOnly); ok {
+		return typ
+	}
+	return "RAW"
+}
+
 func RawQuery[T any](sess Session, query string, args ...any) *RawQuerier[T] {
 	c := sess.getCore()
 	return &RawQuerier[T]{
@@ -29,6 +44,17 @@ func RawQuery[T any](sess Session, query string, args ...any) *RawQuerier[T] {
 	}
 }
 
+// RawQueryOf 用任意的 QueryBuilder 构造查询，结果扫描到 T 里面
+// 例如 RawQueryOf[User](db, Union(s1, s2).All()).GetMulti(ctx)
+func RawQueryOf[T any](sess Session, qb QueryBuilder) *RawQuerier[T] {
+	c := sess.getCore()
+	return &RawQuerier[T]{
+		qb:   qb,
+		sess: sess,
+		core: c,
+	}
+}
+
 func (r *RawQuerier[T]) Exec(ctx context.Context) Result {
 	var err error
 	r.model, err = r.r.Get(new(T))
@@ -59,7 +85,7 @@ func (r *RawQuerier[T]) Get(ctx context.Context) (*T, error) {
 		return nil, err
 	}
 	res := get[T](ctx, r.sess, r.core, &QueryContext{
-		Type:    "RAW",
+		Type:    r.queryType("SELECT"),
 		Builder: r,
 		Model:   r.model,
 	})
@@ -70,6 +96,34 @@ func (r *RawQuerier[T]) Get(ctx context.Context) (*T, error) {
 }
 
 func (r *RawQuerier[T]) GetMulti(ctx context.Context) ([]*T, error) {
-	//TODO implement me
-	panic("implement me")
+	var err error
+	r.model, err = r.r.Get(new(T))
+	if err != nil {
+		return nil, err
+	}
+	res := getMulti[T](ctx, r.sess, r.core, &QueryContext{
+		Type:    r.queryType("SELECT"),
+		Builder: r,
+		Model:   r.model,
+	})
+	if res.Result != nil {
+		return res.Result.([]*T), res.Err
+	}
+	return nil, res.Err
+}
+
+// Iter 和 Selector.Iter 一样，逐行扫描结果集
+func (r *RawQuerier[T]) Iter(ctx context.Context) iter.Seq2[*T, error] {
+	var err error
+	r.model, err = r.r.Get(new(T))
+	if err != nil {
+		return func(yield func(*T, error) bool) {
+			yield(nil, err)
+		}
+	}
+	return iterate[T](ctx, r.sess, r.core, &QueryContext{
+		Type:    r.queryType("ITER"),
+		Builder: r,
+		Model:   r.model,
+	})
 }
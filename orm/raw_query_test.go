@@ -69,3 +69,109 @@ func TestRawQuerier_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestRawQuerier_GetMulti(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	// 对应于 query error
+	mock.ExpectQuery("SELECT .*").WillReturnError(errors.New("query error"))
+
+	// 对应于 no rows
+	rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	// data
+	rows = sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow("1", "Tom", "18", "Jerry")
+	rows.AddRow("2", "Bob", "20", "Allice")
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	testCases := []struct {
+		name string
+		r    *RawQuerier[TestModel]
+
+		wantErr error
+		wantRes []*TestModel
+	}{
+		{
+			name:    "query error",
+			r:       RawQuery[TestModel](db, "SELECT * FROM `test_model`"),
+			wantErr: errors.New("query error"),
+		},
+		{
+			name:    "no rows",
+			r:       RawQuery[TestModel](db, "SELECT * FROM `test_model` WHERE `id` = ?", -1),
+			wantRes: []*TestModel{},
+		},
+		{
+			name: "data",
+			r:    RawQuery[TestModel](db, "SELECT * FROM `test_model` WHERE `id` < ?", 3),
+			wantRes: []*TestModel{
+				{
+					Id:        1,
+					FirstName: "Tom",
+					Age:       18,
+					LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+				},
+				{
+					Id:        2,
+					FirstName: "Bob",
+					Age:       20,
+					LastName:  &sql.NullString{Valid: true, String: "Allice"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.r.GetMulti(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestRawQuerier_Iter(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	var typ string
+	db, err := OpenDB(mockDB, DBWithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, qc *QueryContext) *QueryResult {
+			typ = qc.Type
+			return next(ctx, qc)
+		}
+	}))
+	require.NoError(t, err)
+	rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow("1", "Tom", "18", "Jerry")
+	rows.AddRow("2", "Bob", "20", "Allice")
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	var res []*TestModel
+	for tm, err := range RawQuery[TestModel](db, "SELECT * FROM `test_model`").Iter(context.Background()) {
+		require.NoError(t, err)
+		res = append(res, tm)
+	}
+	// 原生 SQL 不一定是查询，不能标记为 ITER
+	assert.Equal(t, "RAW", typ)
+	assert.Equal(t, []*TestModel{
+		{
+			Id:        1,
+			FirstName: "Tom",
+			Age:       18,
+			LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+		},
+		{
+			Id:        2,
+			FirstName: "Bob",
+			Age:       20,
+			LastName:  &sql.NullString{Valid: true, String: "Allice"},
+		},
+	}, res)
+}
@@ -1,6 +1,9 @@
 package orm
 
-import "database/sql"
+import (
+	"database/sql"
+	"scaffolding-go/orm/internal/errs"
+)
 
 type Result struct {
 	err error
@@ -24,3 +27,23 @@ func (r Result) RowsAffected() (int64, error) {
 func (r Result) Err() error {
 	return r.err
 }
+
+// multiResult 多条语句的执行结果，例如分片或者分批插入
+// RowsAffected 是总和
+type multiResult []sql.Result
+
+func (r multiResult) LastInsertId() (int64, error) {
+	return 0, errs.ErrMultiResultLastInsertId
+}
+
+func (r multiResult) RowsAffected() (int64, error) {
+	var res int64
+	for _, sr := range r {
+		affected, err := sr.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		res += affected
+	}
+	return res, nil
+}
@@ -2,6 +2,7 @@ package orm
 
 import (
 	"context"
+	"iter"
 	"scaffolding-go/orm/internal/errs"
 )
 
@@ -19,7 +20,14 @@ type Selector[T any] struct {
 	having  []Predicate
 	columns []Selectable
 	groupBy []Column
+	orderBy []OrderBy
+	limit   int
+	offset  int
 	sess    Session
+	// unscoped 为 true 的时候不过滤软删除的数据
+	unscoped bool
+	// 需要加载的关联关系
+	preloads []string
 }
 
 func NewSelector[T any](sess Session) *Selector[T] {
@@ -33,7 +41,10 @@ func NewSelector[T any](sess Session) *Selector[T] {
 	}
 }
 
+func (s *Selector[T]) readOnly() {}
+
 func (s *Selector[T]) Build() (*Query, error) {
+	s.reset()
 	if s.model == nil {
 		var err error
 		s.model, err = s.r.Get(new(T))
@@ -66,11 +77,15 @@ func (s *Selector[T]) Build() (*Query, error) {
 	//	//r.sb.WriteByte('`')
 	//	s.sb.WriteString(s.table)
 	//}
-	if len(s.where) > 0 {
+	where, err := s.scopedWhere()
+	if err != nil {
+		return nil, err
+	}
+	if len(where) > 0 {
 		s.sb.WriteString(" WHERE ")
-		p := s.where[0]
-		for i := 1; i < len(s.where); i++ {
-			p = p.And(s.where[i])
+		p := where[0]
+		for i := 1; i < len(where); i++ {
+			p = p.And(where[i])
 		}
 		if err := s.buildExpression(p); err != nil {
 			return nil, err
@@ -87,6 +102,34 @@ func (s *Selector[T]) Build() (*Query, error) {
 			}
 		}
 	}
+	if len(s.having) > 0 {
+		s.sb.WriteString(" HAVING ")
+		p := s.having[0]
+		for i := 1; i < len(s.having); i++ {
+			p = p.And(s.having[i])
+		}
+		if err := s.buildExpression(p); err != nil {
+			return nil, err
+		}
+	}
+	if len(s.orderBy) > 0 {
+		s.sb.WriteString(" ORDER BY ")
+		for i, ob := range s.orderBy {
+			if i > 0 {
+				s.sb.WriteByte(',')
+			}
+			if err := s.buildColumn(Column{name: ob.col}); err != nil {
+				return nil, err
+			}
+			s.sb.WriteByte(' ')
+			s.sb.WriteString(ob.order)
+		}
+	}
+	if s.limit > 0 || s.offset > 0 {
+		if err := s.dialect.buildLimit(&s.builder, s.limit, s.offset); err != nil {
+			return nil, err
+		}
+	}
 	s.sb.WriteByte(';')
 	return &Query{
 		SQL:  s.sb.String(),
@@ -94,6 +137,34 @@ func (s *Selector[T]) Build() (*Query, error) {
 	}, nil
 }
 
+// scopedWhere 在 WHERE 后面加上过滤软删除数据的条件
+// 只处理单表的情况，JOIN 和子查询需要用户自己加条件
+func (s *Selector[T]) scopedWhere() ([]Predicate, error) {
+	if s.unscoped {
+		return s.where, nil
+	}
+	var col Column
+	switch t := s.table.(type) {
+	case nil:
+		if s.model.SoftDelete == nil {
+			return s.where, nil
+		}
+		col = C(s.model.SoftDelete.GoName)
+	case Table:
+		m, err := s.r.Get(t.entity)
+		if err != nil {
+			return nil, err
+		}
+		if m.SoftDelete == nil {
+			return s.where, nil
+		}
+		col = t.C(m.SoftDelete.GoName)
+	default:
+		return s.where, nil
+	}
+	return append(s.where[:len(s.where):len(s.where)], col.IsNull()), nil
+}
+
 func (s *Selector[T]) buildTable(table TableReference) error {
 	switch t := table.(type) {
 	case nil:
@@ -150,64 +221,14 @@ func (s *Selector[T]) buildTable(table TableReference) error {
 			}
 		}
 		s.sb.WriteByte(')')
+	case Subquery:
+		return s.buildSubquery(t, true)
 	default:
 		return errs.NewErrUnsupportedTable(table)
 	}
 	return nil
 }
 
-func (s *Selector[T]) buildExpression(expr Expression) error {
-	switch exp := expr.(type) {
-	case nil:
-	case Predicate:
-		// 在这里处理 p
-		// p.left 构建好
-		// p.op 构建好
-		// p.right 构建好
-		_, ok := exp.left.(Predicate)
-		if ok {
-			s.sb.WriteByte('(')
-		}
-		if err := s.buildExpression(exp.left); err != nil {
-			return err
-		}
-		if ok {
-			s.sb.WriteByte(')')
-		}
-		if exp.op != "" {
-			s.sb.WriteByte(' ')
-			s.sb.WriteString(exp.op.String())
-			s.sb.WriteByte(' ')
-		}
-		_, ok = exp.right.(Predicate)
-		if ok {
-			s.sb.WriteByte('(')
-		}
-		if err := s.buildExpression(exp.right); err != nil {
-			return err
-		}
-		if ok {
-			s.sb.WriteByte(')')
-		}
-
-	case Column:
-		// 这种写法很隐晦
-		exp.alias = ""
-		return s.buildColumn(exp)
-	case value:
-		s.sb.WriteByte('?')
-		s.addArg(exp.val)
-	case RawExpr:
-		s.sb.WriteByte('(')
-		s.sb.WriteString(exp.raw)
-		s.addArg(exp.args...)
-		s.sb.WriteByte(')')
-	default:
-		return errs.NewErrUnsupportedExpressionType(exp)
-	}
-	return nil
-}
-
 func (s *Selector[T]) buildColumns() error {
 	if len(s.columns) == 0 {
 		// 没有指定列
@@ -225,38 +246,42 @@ func (s *Selector[T]) buildColumns() error {
 				return err
 			}
 		case Aggregate:
-			// 聚合函数名
-			s.sb.WriteString(c.fn)
-			s.sb.WriteByte('(')
-			err := s.buildColumn(Column{name: c.arg})
-			if err != nil {
+			if err := s.buildAggregate(c); err != nil {
 				return err
 			}
-			s.sb.WriteByte(')')
 			// 聚合函数本身的别名
 			if c.alias != "" {
-				s.sb.WriteString(" AS `")
-				s.sb.WriteString(c.alias)
-				s.sb.WriteByte('`')
+				s.sb.WriteString(" AS ")
+				s.quote(c.alias)
 			}
 		case RawExpr:
-			s.sb.WriteString(c.raw)
-			s.addArg(c.args...)
+			s.buildRaw(c)
+		case Subquery:
+			// 标量子查询
+			if err := s.buildSubquery(c, true); err != nil {
+				return err
+			}
+		case MathExpr:
+			if err := s.buildExpression(c); err != nil {
+				return err
+			}
+			if c.alias != "" {
+				s.sb.WriteString(" AS ")
+				s.quote(c.alias)
+			}
+		case FuncExpr:
+			if err := s.buildFunc(c); err != nil {
+				return err
+			}
+			if c.alias != "" {
+				s.sb.WriteString(" AS ")
+				s.quote(c.alias)
+			}
 		}
 	}
 	return nil
 }
 
-func (s *Selector[T]) addArg(vals ...any) {
-	if len(vals) == 0 {
-		return
-	}
-	if s.args == nil {
-		s.args = make([]any, 0, 4)
-	}
-	s.args = append(s.args, vals...)
-}
-
 // 简单写法
 //func (r *Selector[T]) Select(cols ...string) *Selector[T] {
 //	r.columns = cols
@@ -278,16 +303,48 @@ func (s *Selector[T]) Where(ps ...Predicate) *Selector[T] {
 	return s
 }
 
+// Preload 查询之后加载关联关系，例如 Preload("Orders")
+// 每个关联关系只会发起一次 IN 查询，Iter 不支持
+// belongs_to 也用 IN 查询，因为 JOIN 的结果没办法同时扫描到两个结构体里面
+func (s *Selector[T]) Preload(names ...string) *Selector[T] {
+	s.preloads = append(s.preloads, names...)
+	return s
+}
+
+// Unscoped 查询包括软删除的数据
+func (s *Selector[T]) Unscoped() *Selector[T] {
+	s.unscoped = true
+	return s
+}
+
 func (s *Selector[T]) GroupBy(cols ...Column) *Selector[T] {
 	s.groupBy = cols
 	return s
 }
 
+// Having 要和 GroupBy 一起使用
+// 例如 Having(Avg("Age").GT(18))
 func (s *Selector[T]) Having(ps ...Predicate) *Selector[T] {
 	s.having = ps
 	return s
 }
 
+// OrderBy 例如 OrderBy(Asc("Age"), Desc("Id"))
+func (s *Selector[T]) OrderBy(obs ...OrderBy) *Selector[T] {
+	s.orderBy = obs
+	return s
+}
+
+func (s *Selector[T]) Limit(limit int) *Selector[T] {
+	s.limit = limit
+	return s
+}
+
+func (s *Selector[T]) Offset(offset int) *Selector[T] {
+	s.offset = offset
+	return s
+}
+
 //func (r *Selector[T]) GetV1(ctx context.Context) (*T, error) {
 //	q, err := r.Build()
 //	// 这个是构造sql失败
@@ -345,13 +402,22 @@ func (s *Selector[T]) Get(ctx context.Context) (*T, error) {
 	if err != nil {
 		return nil, err
 	}
-	res := get[T](ctx, s.sess, s.core, &QueryContext{
-		Type:    "SELECT",
-		Builder: s,
-		Model:   s.model,
-	})
+	var res *QueryResult
+	if s.sharded() {
+		res = s.shardingGet(ctx)
+	} else {
+		res = get[T](ctx, s.sess, s.core, &QueryContext{
+			Type:    "SELECT",
+			Builder: s,
+			Model:   s.model,
+		})
+	}
 	if res.Result != nil {
-		return res.Result.(*T), res.Err
+		t := res.Result.(*T)
+		if res.Err == nil && len(s.preloads) > 0 {
+			res.Err = preload(ctx, s.sess, s.core, []*T{t}, s.preloads)
+		}
+		return t, res.Err
 	}
 	return nil, res.Err
 }
@@ -420,16 +486,58 @@ func (s *Selector[T]) Get(ctx context.Context) (*T, error) {
 //}
 
 func (s *Selector[T]) GetMulti(ctx context.Context) ([]*T, error) {
-	q, err := s.Build()
+	var err error
+	s.model, err = s.r.Get(new(T))
 	if err != nil {
 		return nil, err
 	}
+	var res *QueryResult
+	if s.sharded() {
+		res = s.shardingGetMulti(ctx)
+	} else {
+		res = getMulti[T](ctx, s.sess, s.core, &QueryContext{
+			Type:    "SELECT",
+			Builder: s,
+			Model:   s.model,
+		})
+	}
+	if res.Result != nil {
+		ts := res.Result.([]*T)
+		if res.Err == nil && len(s.preloads) > 0 {
+			res.Err = preload(ctx, s.sess, s.core, ts, s.preloads)
+		}
+		return ts, res.Err
+	}
+	return nil, res.Err
+}
 
-	// 在这里发起查询，并且处理结果集
-	rows, err := s.sess.queryContext(ctx, q.SQL, q.Args...)
-	// 在这里继续处理结果集
-	for rows.Next() {
-		// 构造 []*T
+// Iter 返回一个迭代器，适合遍历大量数据的场景
+// 每次迭代只会扫描一行，不会把结果集全部加载进内存
+//
+//	for u, err := range NewSelector[User](db).Iter(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//	}
+func (s *Selector[T]) Iter(ctx context.Context) iter.Seq2[*T, error] {
+	var err error
+	s.model, err = s.r.Get(new(T))
+	if err == nil && len(s.preloads) > 0 {
+		err = errs.ErrPreloadWithIter
+	}
+	sub := s
+	if err == nil && s.sharded() {
+		// 只支持命中一个分片的查询
+		sub, err = s.shardingIter(ctx)
+	}
+	if err != nil {
+		return func(yield func(*T, error) bool) {
+			yield(nil, err)
+		}
 	}
-	panic("im")
+	return iterate[T](ctx, sub.sess, sub.core, &QueryContext{
+		Type:    "ITER",
+		Builder: sub,
+		Model:   sub.model,
+	})
 }
@@ -66,6 +66,250 @@ func TestSelector_GroupBy(t *testing.T) {
 	}
 }
 
+func TestSelector_Having(t *testing.T) {
+	db := memoryDB(t)
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			// 调用了，但是啥也没传
+			name: "none",
+			q:    NewSelector[TestModel](db).GroupBy(C("Age")).Having(),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` GROUP BY `age`;",
+			},
+		},
+		{
+			// 单个条件
+			name: "single",
+			q: NewSelector[TestModel](db).GroupBy(C("Age")).
+				Having(C("FirstName").Eq("Deng")),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` GROUP BY `age` HAVING `first_name` = ?;",
+				Args: []any{"Deng"},
+			},
+		},
+		{
+			// 多个条件
+			name: "multiple",
+			q: NewSelector[TestModel](db).GroupBy(C("Age")).
+				Having(C("FirstName").Eq("Deng"), C("LastName").Eq("Ming")),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` GROUP BY `age` HAVING (`first_name` = ?) AND (`last_name` = ?);",
+				Args: []any{"Deng", "Ming"},
+			},
+		},
+		{
+			// 聚合函数
+			name: "avg",
+			q: NewSelector[TestModel](db).GroupBy(C("FirstName")).
+				Having(Avg("Age").GT(18), Count("Id").LT(100)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` GROUP BY `first_name` HAVING (AVG(`age`) > ?) AND (COUNT(`id`) < ?);",
+				Args: []any{18, 100},
+			},
+		},
+		{
+			// 别名不会出现在 HAVING 里面
+			name: "aggregate alias",
+			q: NewSelector[TestModel](db).Select(Max("Age").As("max_age")).
+				GroupBy(C("FirstName")).Having(Max("Age").As("max_age").Eq(18)),
+			wantQuery: &Query{
+				SQL:  "SELECT MAX(`age`) AS `max_age` FROM `test_model` GROUP BY `first_name` HAVING MAX(`age`) = ?;",
+				Args: []any{18},
+			},
+		},
+		{
+			name: "invalid aggregate column",
+			q: NewSelector[TestModel](db).GroupBy(C("FirstName")).
+				Having(Avg("Invalid").GT(18)),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}
+
+func TestSelector_OrderBy(t *testing.T) {
+	db := memoryDB(t)
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "asc",
+			q:    NewSelector[TestModel](db).OrderBy(Asc("Age")),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` ORDER BY `age` ASC;",
+			},
+		},
+		{
+			name: "multiple",
+			q:    NewSelector[TestModel](db).OrderBy(Asc("Age"), Desc("Id")),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` ORDER BY `age` ASC,`id` DESC;",
+			},
+		},
+		{
+			name: "with where and group by",
+			q: NewSelector[TestModel](db).Where(C("Id").GT(10)).
+				GroupBy(C("Age")).Having(Count("Id").GT(1)).OrderBy(Desc("Age")),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `id` > ? GROUP BY `age` HAVING COUNT(`id`) > ? ORDER BY `age` DESC;",
+				Args: []any{10, 1},
+			},
+		},
+		{
+			name:    "invalid column",
+			q:       NewSelector[TestModel](db).OrderBy(Asc("Invalid")),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}
+
+func TestSelector_LimitOffset(t *testing.T) {
+	mysqlDB := memoryDB(t)
+	sqliteDB := memoryDB(t, DBWithDialect(DialectSQLite))
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "mysql limit",
+			q:    NewSelector[TestModel](mysqlDB).Limit(10),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` LIMIT ?;",
+				Args: []any{10},
+			},
+		},
+		{
+			name: "mysql limit offset",
+			q:    NewSelector[TestModel](mysqlDB).Where(C("Age").GT(18)).OrderBy(Desc("Id")).Limit(10).Offset(20),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `age` > ? ORDER BY `id` DESC LIMIT ? OFFSET ?;",
+				Args: []any{18, 10, 20},
+			},
+		},
+		{
+			name: "mysql offset only",
+			q:    NewSelector[TestModel](mysqlDB).Offset(20),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` LIMIT 18446744073709551615 OFFSET ?;",
+				Args: []any{20},
+			},
+		},
+		{
+			name: "sqlite limit offset",
+			q:    NewSelector[TestModel](sqliteDB).Limit(10).Offset(20),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` LIMIT ? OFFSET ?;",
+				Args: []any{10, 20},
+			},
+		},
+		{
+			name: "sqlite offset only",
+			q:    NewSelector[TestModel](sqliteDB).Offset(20),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` LIMIT -1 OFFSET ?;",
+				Args: []any{20},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, query)
+		})
+	}
+}
+
+func TestSelector_SoftDelete(t *testing.T) {
+	db := memoryDB(t)
+	testCases := []struct {
+		name      string
+		q         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "no where",
+			q:    NewSelector[SoftDeleteModel](db),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `soft_delete_model` WHERE `deleted_at` IS NULL;",
+			},
+		},
+		{
+			name: "where",
+			q:    NewSelector[SoftDeleteModel](db).Where(C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `soft_delete_model` WHERE (`id` = ?) AND (`deleted_at` IS NULL);",
+				Args: []any{1},
+			},
+		},
+		{
+			name: "table alias",
+			q:    NewSelector[SoftDeleteModel](db).FROM(TableOf(&SoftDeleteModel{}).As("t1")),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `soft_delete_model` AS `t1` WHERE `t1`.`deleted_at` IS NULL;",
+			},
+		},
+		{
+			name: "unscoped",
+			q:    NewSelector[SoftDeleteModel](db).Where(C("Id").Eq(1)).Unscoped(),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `soft_delete_model` WHERE `id` = ?;",
+				Args: []any{1},
+			},
+		},
+		{
+			name: "without soft delete",
+			q:    NewSelector[TestModel](db),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model`;",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.q.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
 func TestSelector_Join(t *testing.T) {
 	db := memoryDB(t)
 	type Order struct {
@@ -191,6 +435,139 @@ func TestSelector_Join(t *testing.T) {
 	}
 }
 
+func TestSelector_Subquery(t *testing.T) {
+	db := memoryDB(t)
+	type Order struct {
+		Id     int
+		UserId int
+		Amount int
+	}
+
+	testCases := []struct {
+		name      string
+		s         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "from",
+			s: func() QueryBuilder {
+				sub := NewSelector[Order](db).Where(C("Amount").GT(100)).AsSubquery("sub")
+				return NewSelector[Order](db).Select(sub.C("UserId")).FROM(sub).
+					Where(sub.C("Id").LT(10))
+			}(),
+			wantQuery: &Query{
+				SQL: "SELECT `sub`.`user_id` FROM (SELECT * FROM `order` WHERE `amount` > ?) AS `sub` " +
+					"WHERE `sub`.`id` < ?;",
+				Args: []any{100, 10},
+			},
+		},
+		{
+			name: "join",
+			s: func() QueryBuilder {
+				t1 := TableOf(&TestModel{}).As("t1")
+				sub := NewSelector[Order](db).Select(C("UserId"), Sum("Amount").As("total")).
+					GroupBy(C("UserId")).AsSubquery("sub")
+				return NewSelector[TestModel](db).Select(t1.C("FirstName"), sub.C("total")).
+					FROM(t1.Join(sub).On(t1.C("Id").Eq(sub.C("UserId")))).
+					Where(t1.C("Age").GT(18))
+			}(),
+			wantQuery: &Query{
+				SQL: "SELECT `t1`.`first_name`,`sub`.`total` FROM (`test_model` AS `t1` JOIN " +
+					"(SELECT `user_id`,SUM(`amount`) AS `total` FROM `order` GROUP BY `user_id`) AS `sub` " +
+					"ON `t1`.`id` = `sub`.`user_id`) WHERE `t1`.`age` > ?;",
+				Args: []any{18},
+			},
+		},
+		{
+			name: "subquery join",
+			s: func() QueryBuilder {
+				t1 := TableOf(&TestModel{})
+				sub := NewSelector[Order](db).AsSubquery("sub")
+				return NewSelector[TestModel](db).FROM(sub.LeftJoin(t1).Using("Id"))
+			}(),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM ((SELECT * FROM `order`) AS `sub` LEFT JOIN `test_model` USING (`id`));",
+			},
+		},
+		{
+			name: "in",
+			s: func() QueryBuilder {
+				sub := NewSelector[Order](db).Select(C("UserId")).
+					Where(C("Amount").GT(100)).AsSubquery("sub")
+				return NewSelector[TestModel](db).Where(C("Age").GT(18), C("Id").InQuery(sub))
+			}(),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` WHERE (`age` > ?) AND " +
+					"(`id` IN (SELECT `user_id` FROM `order` WHERE `amount` > ?));",
+				Args: []any{18, 100},
+			},
+		},
+		{
+			name: "not in",
+			s: func() QueryBuilder {
+				sub := NewSelector[Order](db).Select(C("UserId")).AsSubquery("sub")
+				return NewSelector[TestModel](db).Where(C("Id").NotInQuery(sub))
+			}(),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` WHERE `id` NOT IN (SELECT `user_id` FROM `order`);",
+			},
+		},
+		{
+			name: "exists",
+			s: func() QueryBuilder {
+				sub := NewSelector[Order](db).Where(C("Amount").Eq(10)).AsSubquery("sub")
+				return NewSelector[TestModel](db).Where(Exists(sub), NotExists(sub))
+			}(),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` WHERE ( EXISTS (SELECT * FROM `order` WHERE `amount` = ?)) AND " +
+					"( NOT EXISTS (SELECT * FROM `order` WHERE `amount` = ?));",
+				Args: []any{10, 10},
+			},
+		},
+		{
+			name: "scalar",
+			s: func() QueryBuilder {
+				sub := NewSelector[Order](db).Select(Max("Amount")).
+					Where(C("UserId").Eq(12)).AsSubquery("max_amount")
+				return NewSelector[TestModel](db).Select(C("FirstName"), sub).
+					Where(C("Age").GT(sub))
+			}(),
+			wantQuery: &Query{
+				SQL: "SELECT `first_name`,(SELECT MAX(`amount`) FROM `order` WHERE `user_id` = ?) AS `max_amount` " +
+					"FROM `test_model` WHERE `age` > (SELECT MAX(`amount`) FROM `order` WHERE `user_id` = ?);",
+				Args: []any{12, 12},
+			},
+		},
+		{
+			name: "unselected column",
+			s: func() QueryBuilder {
+				sub := NewSelector[Order](db).Select(C("UserId")).AsSubquery("sub")
+				return NewSelector[Order](db).Select(sub.C("Amount")).FROM(sub)
+			}(),
+			wantErr: errs.NewErrUnknownField("Amount"),
+		},
+		{
+			name: "invalid subquery",
+			s: func() QueryBuilder {
+				sub := NewSelector[Order](db).Where(C("Invalid").Eq(1)).AsSubquery("sub")
+				return NewSelector[TestModel](db).Where(Exists(sub))
+			}(),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.s.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
 func TestSelector_Select(t *testing.T) {
 	db := memoryDB(t)
 	testCases := []struct {
@@ -399,6 +776,197 @@ func TestSelector_Build(t *testing.T) {
 	}
 }
 
+func TestSelector_Predicates(t *testing.T) {
+	testCases := []struct {
+		name      string
+		where     []Predicate
+		having    []Predicate
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name:  "neq",
+			where: []Predicate{C("Age").NEQ(18)},
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `age` != ?;",
+				Args: []any{18},
+			},
+		},
+		{
+			name:  "lteq gteq",
+			where: []Predicate{C("Age").GTEQ(18), C("Age").LTEQ(30)},
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE (`age` >= ?) AND (`age` <= ?);",
+				Args: []any{18, 30},
+			},
+		},
+		{
+			name:  "like",
+			where: []Predicate{C("FirstName").Like("%Tom%").Or(C("LastName").NotLike("J_"))},
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE (`first_name` LIKE ?) OR (`last_name` NOT LIKE ?);",
+				Args: []any{"%Tom%", "J_"},
+			},
+		},
+		{
+			name:  "in",
+			where: []Predicate{C("Id").In(1, 2, 3)},
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `id` IN (?,?,?);",
+				Args: []any{1, 2, 3},
+			},
+		},
+		{
+			name:  "not in",
+			where: []Predicate{C("Id").NotIn(1)},
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `id` NOT IN (?);",
+				Args: []any{1},
+			},
+		},
+		{
+			name:  "empty in",
+			where: []Predicate{C("Id").In(), C("Age").Eq(18)},
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE (FALSE) AND (`age` = ?);",
+				Args: []any{18},
+			},
+		},
+		{
+			name:  "empty not in",
+			where: []Predicate{C("Id").NotIn()},
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` WHERE TRUE;",
+			},
+		},
+		{
+			name:  "between",
+			where: []Predicate{C("Age").Between(18, 30)},
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE `age` BETWEEN ? AND ?;",
+				Args: []any{18, 30},
+			},
+		},
+		{
+			name:  "is null",
+			where: []Predicate{C("LastName").IsNull(), Not(C("FirstName").IsNotNull())},
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` WHERE (`last_name` IS NULL) AND ( NOT (`first_name` IS NOT NULL));",
+			},
+		},
+		{
+			name:   "aggregate",
+			having: []Predicate{Count("Id").GTEQ(2), Avg("Age").Between(18, 30), Max("Age").In(20, 30)},
+			wantQuery: &Query{
+				SQL: "SELECT * FROM `test_model` GROUP BY `first_name` HAVING " +
+					"((COUNT(`id`) >= ?) AND (AVG(`age`) BETWEEN ? AND ?)) AND (MAX(`age`) IN (?,?));",
+				Args: []any{2, 18, 30, 20, 30},
+			},
+		},
+		{
+			name:    "invalid column",
+			where:   []Predicate{C("Invalid").In(1, 2)},
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+	dialects := map[string]Dialect{
+		"mysql":  DialectMySQL,
+		"sqlite": DialectSQLite,
+	}
+	for dn, dialect := range dialects {
+		db := memoryDB(t, DBWithDialect(dialect))
+		for _, tc := range testCases {
+			t.Run(dn+" "+tc.name, func(t *testing.T) {
+				s := NewSelector[TestModel](db).Where(tc.where...)
+				if len(tc.having) > 0 {
+					s = s.GroupBy(C("FirstName")).Having(tc.having...)
+				}
+				q, err := s.Build()
+				assert.Equal(t, tc.wantErr, err)
+				if err != nil {
+					return
+				}
+				assert.Equal(t, tc.wantQuery, q)
+			})
+		}
+	}
+}
+
+func TestSelector_Expressions(t *testing.T) {
+	db := memoryDB(t)
+	testCases := []struct {
+		name      string
+		s         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "math in select",
+			s:    NewSelector[TestModel](db).Select(C("Id"), C("Age").Add(1).As("next_age")),
+			wantQuery: &Query{
+				SQL:  "SELECT `id`,`age` + ? AS `next_age` FROM `test_model`;",
+				Args: []any{1},
+			},
+		},
+		{
+			name: "nested math",
+			s: NewSelector[TestModel](db).Select(C("Age").Sub(C("Id").Div(2)).Multi(3)).
+				Where(C("Age").Multi(2).GT(C("Id").Sub(1))),
+			wantQuery: &Query{
+				SQL:  "SELECT (`age` - (`id` / ?)) * ? FROM `test_model` WHERE (`age` * ?) > (`id` - ?);",
+				Args: []any{2, 3, 2, 1},
+			},
+		},
+		{
+			name: "func in select",
+			s: NewSelector[TestModel](db).Select(
+				Lower(C("FirstName")).As("lower_name"),
+				Concat(C("FirstName"), " ", Upper(C("LastName"))),
+				Coalesce(Max("Age"), 0).As("max_age"),
+				Now().As("now"),
+			),
+			wantQuery: &Query{
+				SQL: "SELECT LOWER(`first_name`) AS `lower_name`,CONCAT(`first_name`,?,UPPER(`last_name`))," +
+					"COALESCE(MAX(`age`),?) AS `max_age`,CURRENT_TIMESTAMP AS `now` FROM `test_model`;",
+				Args: []any{" ", 0},
+			},
+		},
+		{
+			name: "sqlite concat",
+			s: NewSelector[TestModel](memoryDB(t, DBWithDialect(DialectSQLite))).
+				Select(Concat(C("FirstName"), " ", Upper(C("LastName")))),
+			wantQuery: &Query{
+				SQL:  "SELECT (`first_name` || ? || UPPER(`last_name`)) FROM `test_model`;",
+				Args: []any{" "},
+			},
+		},
+		{
+			name: "func in where",
+			s: NewSelector[TestModel](db).Where(Lower(C("FirstName")).Eq("tom"),
+				Coalesce(C("Age"), 0).Add(1).LTEQ(18)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM `test_model` WHERE (LOWER(`first_name`) = ?) AND ((COALESCE(`age`,?) + ?) <= ?);",
+				Args: []any{"tom", 0, 1, 18},
+			},
+		},
+		{
+			name:    "invalid column in func",
+			s:       NewSelector[TestModel](db).Select(Upper(C("Invalid"))),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.s.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
 // memoryDB 返回一个基于内存的 ORM，它使用的是 sqlite3 内存模式。
 func memoryDB(t *testing.T, opts ...DBOption) *DB {
 	orm, err := Open("sqlite3", "file:test.db?cache=shared&mode=memory",
@@ -491,6 +1059,217 @@ func TestSelector_Get(t *testing.T) {
 	}
 }
 
+func TestSelector_GetMulti(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	// 对应于 query error
+	mock.ExpectQuery("SELECT .*").WillReturnError(errors.New("query error"))
+
+	// 对应于 no rows
+	rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	// data
+	rows = sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow("1", "Tom", "18", "Jerry")
+	rows.AddRow("2", "Bob", "20", "Allice")
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	// 遍历过程中出错
+	rows = sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow("1", "Tom", "18", "Jerry")
+	rows.AddRow("2", "Bob", "20", "Allice")
+	rows.RowError(1, errors.New("row error"))
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	testCases := []struct {
+		name string
+		s    *Selector[TestModel]
+
+		wantErr error
+		wantRes []*TestModel
+	}{
+		{
+			name:    "invalid query",
+			s:       NewSelector[TestModel](db).Where(C("xxx").Eq(1)),
+			wantErr: errs.NewErrUnknownField("xxx"),
+		},
+		{
+			name:    "query error",
+			s:       NewSelector[TestModel](db).Where(C("Id").Eq(1)),
+			wantErr: errors.New("query error"),
+		},
+		{
+			name:    "no rows",
+			s:       NewSelector[TestModel](db).Where(C("Id").Eq(1)),
+			wantRes: []*TestModel{},
+		},
+		{
+			name: "data",
+			s:    NewSelector[TestModel](db).Where(C("Id").LT(3)),
+			wantRes: []*TestModel{
+				{
+					Id:        1,
+					FirstName: "Tom",
+					Age:       18,
+					LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+				},
+				{
+					Id:        2,
+					FirstName: "Bob",
+					Age:       20,
+					LastName:  &sql.NullString{Valid: true, String: "Allice"},
+				},
+			},
+		},
+		{
+			name:    "row error",
+			s:       NewSelector[TestModel](db).Where(C("Id").LT(3)),
+			wantErr: errors.New("row error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.s.GetMulti(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestSelector_Iter(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	var typ string
+	db, err := OpenDB(mockDB, DBWithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, qc *QueryContext) *QueryResult {
+			typ = qc.Type
+			return next(ctx, qc)
+		}
+	}))
+	require.NoError(t, err)
+
+	// 对应于 query error
+	mock.ExpectQuery("SELECT .*").WillReturnError(errors.New("query error"))
+
+	// data
+	rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow("1", "Tom", "18", "Jerry")
+	rows.AddRow("2", "Bob", "20", "Allice")
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	// 中途 break，只要第一行
+	rows = sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow("1", "Tom", "18", "Jerry")
+	rows.AddRow("2", "Bob", "20", "Allice")
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows).RowsWillBeClosed()
+
+	testCases := []struct {
+		name  string
+		s     *Selector[TestModel]
+		limit int
+
+		wantErr error
+		wantRes []*TestModel
+	}{
+		{
+			name:    "invalid query",
+			s:       NewSelector[TestModel](db).Where(C("xxx").Eq(1)),
+			wantErr: errs.NewErrUnknownField("xxx"),
+		},
+		{
+			name:    "query error",
+			s:       NewSelector[TestModel](db).Where(C("Id").Eq(1)),
+			wantErr: errors.New("query error"),
+		},
+		{
+			name: "data",
+			s:    NewSelector[TestModel](db).Where(C("Id").LT(3)),
+			wantRes: []*TestModel{
+				{
+					Id:        1,
+					FirstName: "Tom",
+					Age:       18,
+					LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+				},
+				{
+					Id:        2,
+					FirstName: "Bob",
+					Age:       20,
+					LastName:  &sql.NullString{Valid: true, String: "Allice"},
+				},
+			},
+		},
+		{
+			name:  "break",
+			s:     NewSelector[TestModel](db).Where(C("Id").LT(3)),
+			limit: 1,
+			wantRes: []*TestModel{
+				{
+					Id:        1,
+					FirstName: "Tom",
+					Age:       18,
+					LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var res []*TestModel
+			var err error
+			for tm, e := range tc.s.Iter(context.Background()) {
+				if e != nil {
+					err = e
+					break
+				}
+				res = append(res, tm)
+				if tc.limit > 0 && len(res) == tc.limit {
+					break
+				}
+			}
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, "ITER", typ)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSelector_IterCanceled(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow("1", "Tom", "18", "Jerry")
+	rows.AddRow("2", "Bob", "20", "Allice")
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cnt := 0
+	for _, err = range NewSelector[TestModel](db).Iter(ctx) {
+		if err != nil {
+			break
+		}
+		cnt++
+		cancel()
+	}
+	assert.Equal(t, 1, cnt)
+	assert.Equal(t, context.Canceled, err)
+}
+
 func (TestModel) CreateSQL() string {
 	return `
 CREATE TABLE IF NOT EXISTS test_model(
@@ -0,0 +1,600 @@
+package orm
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"scaffolding-go/orm/internal/errs"
+	"scaffolding-go/orm/internal/valuer"
+	"scaffolding-go/orm/model"
+	"scaffolding-go/orm/sharding"
+	"sort"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DBWithShards 配置分片所在的数据库，key 对应 sharding.Dst 的 DB
+// 模型的分片算法参考 model.WithSharding
+func DBWithShards(dbs map[string]*sql.DB) DBOption {
+	return func(db *DB) {
+		db.shards = dbs
+	}
+}
+
+// shardSession 分片所在的数据库，name 为空的时候就是 sess 本身
+// 事务只能访问 sess 本身，不能跨库
+func shardSession(ctx context.Context, sess Session, name string) (Session, error) {
+	if name == "" {
+		return sess, nil
+	}
+	db, ok := sess.(*DB)
+	if !ok {
+		return nil, errs.NewErrShardingUnsupported("跨库事务")
+	}
+	if _, ok = db.txFromContext(ctx); ok {
+		return nil, errs.NewErrShardingUnsupported("跨库事务")
+	}
+	sdb, ok := db.shards[name]
+	if !ok {
+		return nil, errs.NewErrUnknownShardDB(name)
+	}
+	return &DB{
+		core: db.core,
+		db:   sdb,
+	}, nil
+}
+
+// shardModel 复制一份元数据，表名换成分片的表名
+func shardModel(m *model.Model, dst sharding.Dst) *model.Model {
+	res := *m
+	res.TableName = dst.Table
+	return &res
+}
+
+// shardingOps 可以用来缩小分片范围的操作符
+var shardingOps = map[op]sharding.Op{
+	opEq:   sharding.OpEQ,
+	opLT:   sharding.OpLT,
+	opLTEQ: sharding.OpLTEQ,
+	opGT:   sharding.OpGT,
+	opGTEQ: sharding.OpGTEQ,
+}
+
+// shardingDsts 根据 WHERE 条件计算命中的分片
+// AND 取交集，OR 取并集，和分片键无关的条件命中全部分片
+func shardingDsts(alg sharding.Algorithm, where []Predicate) ([]sharding.Dst, error) {
+	if len(where) == 0 {
+		return alg.Broadcast(), nil
+	}
+	p := where[0]
+	for i := 1; i < len(where); i++ {
+		p = p.And(where[i])
+	}
+	return predicateDsts(alg, p)
+}
+
+func predicateDsts(alg sharding.Algorithm, p Predicate) ([]sharding.Dst, error) {
+	if p.op == opAnd || p.op == opOr {
+		left, ok1 := p.left.(Predicate)
+		right, ok2 := p.right.(Predicate)
+		if !ok1 || !ok2 {
+			return alg.Broadcast(), nil
+		}
+		l, err := predicateDsts(alg, left)
+		if err != nil {
+			return nil, err
+		}
+		r, err := predicateDsts(alg, right)
+		if err != nil {
+			return nil, err
+		}
+		if p.op == opAnd {
+			return intersectDsts(l, r), nil
+		}
+		return unionDsts(l, r), nil
+	}
+	col, ok := p.left.(Column)
+	if !ok || col.table != nil || col.name != alg.ShardingKey() {
+		return alg.Broadcast(), nil
+	}
+	switch right := p.right.(type) {
+	case value:
+		if sop, ok := shardingOps[p.op]; ok {
+			return alg.Sharding(sop, right.val)
+		}
+	case valueList:
+		if p.op != opIn {
+			break
+		}
+		// IN 没有参数的时候不会命中任何分片
+		var res []sharding.Dst
+		for _, val := range right.vals {
+			dsts, err := alg.Sharding(sharding.OpEQ, val)
+			if err != nil {
+				return nil, err
+			}
+			res = unionDsts(res, dsts)
+		}
+		return res, nil
+	case betweenExpr:
+		start, ok1 := right.start.(value)
+		end, ok2 := right.end.(value)
+		if !ok1 || !ok2 {
+			break
+		}
+		l, err := alg.Sharding(sharding.OpGTEQ, start.val)
+		if err != nil {
+			return nil, err
+		}
+		r, err := alg.Sharding(sharding.OpLTEQ, end.val)
+		if err != nil {
+			return nil, err
+		}
+		return intersectDsts(l, r), nil
+	}
+	return alg.Broadcast(), nil
+}
+
+// intersectDsts 保持 l 的顺序
+func intersectDsts(l, r []sharding.Dst) []sharding.Dst {
+	set := make(map[sharding.Dst]struct{}, len(r))
+	for _, dst := range r {
+		set[dst] = struct{}{}
+	}
+	res := make([]sharding.Dst, 0, len(l))
+	for _, dst := range l {
+		if _, ok := set[dst]; ok {
+			res = append(res, dst)
+		}
+	}
+	return res
+}
+
+// unionDsts 保持 l 的顺序，r 里面新的分片追加在后面
+func unionDsts(l, r []sharding.Dst) []sharding.Dst {
+	set := make(map[sharding.Dst]struct{}, len(l))
+	for _, dst := range l {
+		set[dst] = struct{}{}
+	}
+	res := append(make([]sharding.Dst, 0, len(l)+len(r)), l...)
+	for _, dst := range r {
+		if _, ok := set[dst]; !ok {
+			set[dst] = struct{}{}
+			res = append(res, dst)
+		}
+	}
+	return res
+}
+
+// shardingWhere 没有 WHERE 的时候，按照实体的分片键路由
+func shardingWhere[T any](b *builder, where []Predicate, val *T) ([]Predicate, error) {
+	if len(where) > 0 || val == nil {
+		return where, nil
+	}
+	key := b.model.Sharding.ShardingKey()
+	arg, err := b.creator(b.model, val).Field(key)
+	if err != nil {
+		return nil, err
+	}
+	return []Predicate{C(key).Eq(arg)}, nil
+}
+
+// shardingExec 在每个分片上执行 fn
+func shardingExec(ctx context.Context, sess Session, dsts []sharding.Dst,
+	fn func(ctx context.Context, dst sharding.Dst) (sql.Result, error)) (sql.Result, error) {
+	res := make(multiResult, len(dsts))
+	err := shardingDo(ctx, sess, len(dsts), func(ctx context.Context, i int) error {
+		var err error
+		res[i], err = fn(ctx, dsts[i])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 1 {
+		return res[0], nil
+	}
+	return res, nil
+}
+
+// shardingDo 对 n 个分片执行 fn，一般是并发执行
+// 事务里面所有的分片共用一个连接，交替查询会让驱动出错，所以只能一个一个执行
+func shardingDo(ctx context.Context, sess Session, n int, fn func(ctx context.Context, i int) error) error {
+	if sessionTx(ctx, sess) != nil {
+		for i := 0; i < n; i++ {
+			if err := fn(ctx, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	eg, egCtx := errgroup.WithContext(ctx)
+	for i := 0; i < n; i++ {
+		eg.Go(func() error {
+			return fn(egCtx, i)
+		})
+	}
+	return eg.Wait()
+}
+
+func (s *Selector[T]) sharded() bool {
+	return s.model.Sharding != nil && s.table == nil
+}
+
+// shard 复制一个只查询 dst 的 Selector
+func (s *Selector[T]) shard(ctx context.Context, dst sharding.Dst) (*Selector[T], error) {
+	sess, err := shardSession(ctx, s.sess, dst.DB)
+	if err != nil {
+		return nil, err
+	}
+	// 复制之后 Build 会重置 sb 和 args
+	sub := *s
+	sub.sess = sess
+	sub.model = shardModel(s.model, dst)
+	return &sub, nil
+}
+
+// shardingIter 命中多个分片的时候没办法边遍历边合并
+func (s *Selector[T]) shardingIter(ctx context.Context) (*Selector[T], error) {
+	dsts, err := shardingDsts(s.model.Sharding, s.where)
+	if err != nil {
+		return nil, err
+	}
+	if len(dsts) != 1 {
+		return nil, errs.NewErrShardingUnsupported("Iter")
+	}
+	return s.shard(ctx, dsts[0])
+}
+
+func (s *Selector[T]) shardingGet(ctx context.Context) *QueryResult {
+	res := s.shardingQuery(ctx, true)
+	if res.Err != nil {
+		return res
+	}
+	ts := res.Result.([]*T)
+	if len(ts) == 0 {
+		return &QueryResult{
+			Err: ErrNoRows,
+		}
+	}
+	return &QueryResult{
+		Result: ts[0],
+	}
+}
+
+func (s *Selector[T]) shardingGetMulti(ctx context.Context) *QueryResult {
+	return s.shardingQuery(ctx, false)
+}
+
+// shardingQuery 在命中的分片上并发查询，再合并结果
+// 多个分片的时候，ORDER BY 和 LIMIT 在内存里面重新处理，聚合函数会合并为一行
+// first 代表只需要第一行，每个分片最多查出 offset + 1 行
+func (s *Selector[T]) shardingQuery(ctx context.Context, first bool) *QueryResult {
+	dsts, err := shardingDsts(s.model.Sharding, s.where)
+	if err == nil && len(dsts) > 1 {
+		err = s.checkMerge()
+	}
+	if err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
+	results := make([][]*T, len(dsts))
+	err = shardingDo(ctx, s.sess, len(dsts), func(ctx context.Context, i int) error {
+		sub, err := s.shard(ctx, dsts[i])
+		if err != nil {
+			return err
+		}
+		if len(dsts) > 1 {
+			// 每个分片都要查出 offset + limit 行，合并之后再跳过 offset
+			sub.offset = 0
+			switch {
+			case first && !s.aggregated():
+				sub.limit = s.offset + 1
+			case s.limit > 0:
+				sub.limit = s.limit + s.offset
+			}
+		}
+		res := getMulti[T](ctx, sub.sess, sub.core, &QueryContext{
+			Type:    "SELECT",
+			Builder: sub,
+			Model:   sub.model,
+		})
+		if res.Err != nil {
+			return res.Err
+		}
+		results[i] = res.Result.([]*T)
+		return nil
+	})
+	if err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
+	res := make([]*T, 0, 8)
+	for _, ts := range results {
+		res = append(res, ts...)
+	}
+	if len(dsts) > 1 {
+		res, err = s.merge(res)
+	}
+	return &QueryResult{
+		Result: res,
+		Err:    err,
+	}
+}
+
+// checkMerge 检查多个分片的结果能不能合并
+// 聚合函数需要用别名对应到模型的列上，例如 Count("Id").As("id")
+func (s *Selector[T]) checkMerge() error {
+	if len(s.groupBy) > 0 || len(s.having) > 0 {
+		return errs.NewErrShardingUnsupported("GROUP BY")
+	}
+	for _, col := range s.columns {
+		agg, ok := col.(Aggregate)
+		if !ok {
+			continue
+		}
+		if agg.fn == "AVG" {
+			return errs.NewErrShardingUnsupported("AVG")
+		}
+		if _, ok = s.model.ColumnMap[agg.alias]; !ok {
+			return errs.NewErrUnknownColumn(agg.alias)
+		}
+	}
+	return nil
+}
+
+// aggregated 有聚合函数的时候，每个分片只有一行
+func (s *Selector[T]) aggregated() bool {
+	for _, col := range s.columns {
+		if _, ok := col.(Aggregate); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Selector[T]) merge(ts []*T) ([]*T, error) {
+	if s.aggregated() {
+		return s.mergeAggregate(ts)
+	}
+	if len(s.orderBy) > 0 {
+		sort.SliceStable(ts, func(i, j int) bool {
+			vi, vj := reflect.ValueOf(ts[i]).Elem(), reflect.ValueOf(ts[j]).Elem()
+			for _, ob := range s.orderBy {
+				fi, _ := valuer.FieldByName(vi, ob.col, false)
+				fj, _ := valuer.FieldByName(vj, ob.col, false)
+				c := compareValue(fi, fj)
+				if c == 0 {
+					continue
+				}
+				if ob.order == "DESC" {
+					return c > 0
+				}
+				return c < 0
+			}
+			return false
+		})
+	}
+	if s.offset > 0 {
+		ts = ts[min(s.offset, len(ts)):]
+	}
+	if s.limit > 0 && len(ts) > s.limit {
+		ts = ts[:s.limit]
+	}
+	return ts, nil
+}
+
+// mergeAggregate 没有 GROUP BY，每个分片只有一行，合并到第一行上
+func (s *Selector[T]) mergeAggregate(ts []*T) ([]*T, error) {
+	if len(ts) == 0 {
+		return ts, nil
+	}
+	res := reflect.ValueOf(ts[0]).Elem()
+	for _, col := range s.columns {
+		agg, ok := col.(Aggregate)
+		if !ok {
+			continue
+		}
+		fd := s.model.ColumnMap[agg.alias]
+		dst, _ := valuer.FieldByName(res, fd.GoName, true)
+		for _, t := range ts[1:] {
+			src, _ := valuer.FieldByName(reflect.ValueOf(t).Elem(), fd.GoName, false)
+			if err := mergeValue(agg.fn, dst, src); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ts[:1], nil
+}
+
+// mergeValue 把 src 合并到 dst 上
+// COUNT 和 SUM 相加，MAX 和 MIN 取最大最小，NULL 会被忽略
+func mergeValue(fn string, dst, src reflect.Value) error {
+	if !src.IsValid() || (src.Kind() == reflect.Ptr && src.IsNil()) {
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr && dst.IsNil() {
+		dst.Set(reflect.New(dst.Type().Elem()))
+		dst.Elem().Set(src.Elem())
+		return nil
+	}
+	switch fn {
+	case "MAX", "MIN":
+		c := compareValue(src, dst)
+		if (fn == "MAX" && c > 0) || (fn == "MIN" && c < 0) {
+			dst.Set(src)
+		}
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		dst, src = dst.Elem(), src.Elem()
+	}
+	switch {
+	case dst.CanInt():
+		dst.SetInt(dst.Int() + src.Int())
+	case dst.CanUint():
+		dst.SetUint(dst.Uint() + src.Uint())
+	case dst.CanFloat():
+		dst.SetFloat(dst.Float() + src.Float())
+	default:
+		return errs.NewErrShardingUnsupported(fn + "(" + dst.Type().String() + ")")
+	}
+	return nil
+}
+
+// compareValue 比较同一个字段的两个值，NULL 最小
+func compareValue(a, b reflect.Value) int {
+	x, y := plainValue(a), plainValue(b)
+	switch {
+	case x == nil && y == nil:
+		return 0
+	case x == nil:
+		return -1
+	case y == nil:
+		return 1
+	}
+	switch xv := x.(type) {
+	case int64:
+		return cmp.Compare(xv, y.(int64))
+	case uint64:
+		return cmp.Compare(xv, y.(uint64))
+	case float64:
+		return cmp.Compare(xv, y.(float64))
+	case string:
+		return cmp.Compare(xv, y.(string))
+	case time.Time:
+		return xv.Compare(y.(time.Time))
+	default:
+		return 0
+	}
+}
+
+// plainValue 去掉指针和 sql.NullXXX 之类的包装
+func plainValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if dv, ok := v.Interface().(driver.Valuer); ok {
+		val, err := dv.Value()
+		if err != nil || val == nil {
+			return nil
+		}
+		v = reflect.ValueOf(val)
+	}
+	switch {
+	case v.CanInt():
+		return v.Int()
+	case v.CanUint():
+		return v.Uint()
+	case v.CanFloat():
+		return v.Float()
+	case v.Kind() == reflect.String:
+		return v.String()
+	default:
+		return v.Interface()
+	}
+}
+
+// shardingExec 按照分片键把数据分组，每个分片一条 INSERT 语句
+func (i *Inserter[T]) shardingExec(ctx context.Context) (sql.Result, error) {
+	key := i.model.Sharding.ShardingKey()
+	groups := make(map[sharding.Dst][]*T, 4)
+	dsts := make([]sharding.Dst, 0, 4)
+	for _, val := range i.values {
+		arg, err := i.creator(i.model, val).Field(key)
+		if err != nil {
+			return nil, err
+		}
+		ds, err := i.model.Sharding.Sharding(sharding.OpEQ, arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(ds) != 1 {
+			return nil, errs.NewErrInvalidShardingKey(key, arg)
+		}
+		if _, ok := groups[ds[0]]; !ok {
+			dsts = append(dsts, ds[0])
+		}
+		groups[ds[0]] = append(groups[ds[0]], val)
+	}
+	return shardingExec(ctx, i.sess, dsts, func(ctx context.Context, dst sharding.Dst) (sql.Result, error) {
+		sess, err := shardSession(ctx, i.sess, dst.DB)
+		if err != nil {
+			return nil, err
+		}
+		sub := *i
+		sub.sess = sess
+		sub.model = shardModel(i.model, dst)
+		sub.values = groups[dst]
+		return sub.exec(ctx)
+	})
+}
+
+// shardingExec 按照 WHERE 条件，没有的话按照实体的分片键，在命中的分片上执行
+func (u *Updater[T]) shardingExec(ctx context.Context) (sql.Result, error) {
+	where, err := shardingWhere(&u.builder, u.where, u.val)
+	if err != nil {
+		return nil, err
+	}
+	dsts, err := shardingDsts(u.model.Sharding, where)
+	if err != nil {
+		return nil, err
+	}
+	// 所有分片使用同一个更新时间，并发执行之前准备好，goroutine 里面只读 snap
+	now := u.now()
+	snap := *u
+	snap.now = func() time.Time {
+		return now
+	}
+	res, err := shardingExec(ctx, u.sess, dsts, func(ctx context.Context, dst sharding.Dst) (sql.Result, error) {
+		sess, err := shardSession(ctx, snap.sess, dst.DB)
+		if err != nil {
+			return nil, err
+		}
+		sub := snap
+		sub.sess = sess
+		sub.model = shardModel(snap.model, dst)
+		return sub.exec(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 由 Exec 统一写回实体
+	if u.val != nil {
+		u.autoValues = u.autoUpdateValues(now)
+	}
+	return res, nil
+}
+
+// shardingExec 和 Updater 一样
+func (d *Deleter[T]) shardingExec(ctx context.Context) (sql.Result, error) {
+	where, err := shardingWhere(&d.builder, d.where, d.val)
+	if err != nil {
+		return nil, err
+	}
+	dsts, err := shardingDsts(d.model.Sharding, where)
+	if err != nil {
+		return nil, err
+	}
+	return shardingExec(ctx, d.sess, dsts, func(ctx context.Context, dst sharding.Dst) (sql.Result, error) {
+		sess, err := shardSession(ctx, d.sess, dst.DB)
+		if err != nil {
+			return nil, err
+		}
+		sub := *d
+		sub.sess = sess
+		sub.model = shardModel(d.model, dst)
+		return sub.exec(ctx)
+	})
+}
@@ -0,0 +1,116 @@
+package sharding
+
+import "time"
+
+// DateUnit 按照日期分表的粒度
+type DateUnit uint8
+
+const (
+	UnitDay DateUnit = iota + 1
+	UnitMonth
+	UnitYear
+)
+
+// Date 按照时间分表，例如按月分表的 order_tab_202601
+// 分片键必须是 time.Time 或者 *time.Time
+type Date struct {
+	Key string
+	// DB 所有的表都在同一个库上，空字符串代表默认的库
+	DB string
+	// TablePrefix 表名是 TablePrefix 加上格式化之后的日期，例如 order_tab_
+	TablePrefix string
+	Unit        DateUnit
+	// Start 和 End 是已经建好的表的范围，包括 End 所在的表
+	// 范围查询和 Broadcast 只会用到这些表
+	Start time.Time
+	End   time.Time
+}
+
+func (d *Date) ShardingKey() string {
+	return d.Key
+}
+
+func (d *Date) Broadcast() []Dst {
+	return d.between(d.Start, d.End)
+}
+
+func (d *Date) Sharding(op Op, val any) ([]Dst, error) {
+	if d.Unit < UnitDay || d.Unit > UnitYear {
+		return nil, ErrInvalidConfig
+	}
+	var t time.Time
+	switch v := val.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			return nil, NewErrUnsupportedKey(val)
+		}
+		t = *v
+	default:
+		return nil, NewErrUnsupportedKey(val)
+	}
+	switch op {
+	case OpEQ:
+		return []Dst{d.dst(t)}, nil
+	case OpLT, OpLTEQ:
+		return d.between(d.Start, t), nil
+	case OpGT, OpGTEQ:
+		return d.between(t, d.End), nil
+	default:
+		return d.Broadcast(), nil
+	}
+}
+
+// between [start, end] 之间的表，不会超出 Start 和 End 的范围
+func (d *Date) between(start, end time.Time) []Dst {
+	if start.Before(d.Start) {
+		start = d.Start
+	}
+	if end.After(d.End) {
+		end = d.End
+	}
+	var res []Dst
+	for t := d.truncate(start); !t.After(end); t = d.next(t) {
+		res = append(res, d.dst(t))
+	}
+	return res
+}
+
+func (d *Date) dst(t time.Time) Dst {
+	var layout string
+	switch d.Unit {
+	case UnitDay:
+		layout = "20060102"
+	case UnitMonth:
+		layout = "200601"
+	default:
+		layout = "2006"
+	}
+	return Dst{
+		DB:    d.DB,
+		Table: d.TablePrefix + t.Format(layout),
+	}
+}
+
+func (d *Date) truncate(t time.Time) time.Time {
+	switch d.Unit {
+	case UnitDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case UnitMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+func (d *Date) next(t time.Time) time.Time {
+	switch d.Unit {
+	case UnitDay:
+		return t.AddDate(0, 0, 1)
+	case UnitMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(1, 0, 0)
+	}
+}
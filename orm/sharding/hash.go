@@ -0,0 +1,69 @@
+package sharding
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// Hash 按照 分片键 % TableCount 分表，表按照顺序平均分到 DBCount 个库上
+// 例如 TableCount 是 32，DBCount 是 4，那么 0 ~ 7 号表在 0 号库
+// 字符串的分片键会先计算 FNV 哈希
+type Hash struct {
+	Key string
+	// DBPattern 例如 order_db_%d，空字符串代表都在默认的库上
+	DBPattern string
+	DBCount   int
+	// TablePattern 例如 order_tab_%02d
+	TablePattern string
+	TableCount   int
+}
+
+func (h *Hash) ShardingKey() string {
+	return h.Key
+}
+
+func (h *Hash) Broadcast() []Dst {
+	res := make([]Dst, 0, h.TableCount)
+	for i := 0; i < h.TableCount; i++ {
+		res = append(res, h.dst(i))
+	}
+	return res
+}
+
+// Sharding 只有等值条件可以确定分片，范围条件返回全部分片
+func (h *Hash) Sharding(op Op, val any) ([]Dst, error) {
+	if h.TableCount <= 0 || (h.DBPattern != "" && h.DBCount <= 0) {
+		return nil, ErrInvalidConfig
+	}
+	if op != OpEQ {
+		return h.Broadcast(), nil
+	}
+	var key uint64
+	if s, ok := val.(string); ok {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(s))
+		key = uint64(hash.Sum32())
+	} else {
+		k, ok := toInt64(val)
+		if !ok {
+			return nil, NewErrUnsupportedKey(val)
+		}
+		// 负数也要落在 [0, TableCount) 里面
+		k %= int64(h.TableCount)
+		if k < 0 {
+			k += int64(h.TableCount)
+		}
+		key = uint64(k)
+	}
+	return []Dst{h.dst(int(key % uint64(h.TableCount)))}, nil
+}
+
+func (h *Hash) dst(idx int) Dst {
+	res := Dst{
+		Table: fmt.Sprintf(h.TablePattern, idx),
+	}
+	if h.DBPattern != "" {
+		res.DB = fmt.Sprintf(h.DBPattern, idx*h.DBCount/h.TableCount)
+	}
+	return res
+}
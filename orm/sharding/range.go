@@ -0,0 +1,57 @@
+package sharding
+
+// Range 按照分片键的区间分片，例如 id 在 [0, 1000000) 的在 order_tab_0
+type Range struct {
+	Key string
+	// Ranges 按照 Start 从小到大排列，不能重叠
+	Ranges []RangeShard
+}
+
+// RangeShard 代表 [Start, End) 区间的数据在 Dst 上
+type RangeShard struct {
+	Start int64
+	End   int64
+	Dst   Dst
+}
+
+func (r *Range) ShardingKey() string {
+	return r.Key
+}
+
+func (r *Range) Broadcast() []Dst {
+	res := make([]Dst, 0, len(r.Ranges))
+	for _, rs := range r.Ranges {
+		res = append(res, rs.Dst)
+	}
+	return res
+}
+
+// Sharding 值不在任何区间里面的时候返回空切片
+func (r *Range) Sharding(op Op, val any) ([]Dst, error) {
+	key, ok := toInt64(val)
+	if !ok {
+		return nil, NewErrUnsupportedKey(val)
+	}
+	res := make([]Dst, 0, 1)
+	for _, rs := range r.Ranges {
+		var hit bool
+		switch op {
+		case OpEQ:
+			hit = rs.Start <= key && key < rs.End
+		case OpLT:
+			hit = rs.Start < key
+		case OpLTEQ:
+			hit = rs.Start <= key
+		case OpGT:
+			hit = rs.End-1 > key
+		case OpGTEQ:
+			hit = rs.End > key
+		default:
+			return r.Broadcast(), nil
+		}
+		if hit {
+			res = append(res, rs.Dst)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,66 @@
+package sharding
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	ErrInvalidConfig = errors.New("sharding: 非法的分片配置")
+)
+
+// NewErrUnsupportedKey 代表分片键的值的类型，算法没办法处理
+func NewErrUnsupportedKey(val any) error {
+	return fmt.Errorf("sharding: 不支持的分片键 %v(%T)", val, val)
+}
+
+// Dst 代表一个分片，也就是某个数据库上的某张表
+type Dst struct {
+	// DB 数据库的名字，对应 orm.DBWithShards 的 key
+	// 空字符串代表 orm.DB 自身
+	DB    string
+	Table string
+}
+
+// Op 分片键上的条件
+type Op uint8
+
+const (
+	OpEQ Op = iota + 1
+	OpLT
+	OpLTEQ
+	OpGT
+	OpGTEQ
+)
+
+// Algorithm 分片算法
+// 例如 user_id % 32 分到 order_tab_00 ~ order_tab_31
+type Algorithm interface {
+	// ShardingKey 分片键的字段名，例如 UserId
+	ShardingKey() string
+	// Broadcast 所有的分片，按照固定的顺序返回
+	Broadcast() []Dst
+	// Sharding 计算 分片键 op val 命中的分片
+	// 算法没办法缩小范围的时候，返回 Broadcast
+	Sharding(op Op, val any) ([]Dst, error)
+}
+
+// toInt64 分片键统一转换成 int64，支持所有的整数以及它们的指针
+func toInt64(val any) (int64, bool) {
+	v := reflect.ValueOf(val)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.CanInt():
+		return v.Int(), true
+	case v.CanUint():
+		return int64(v.Uint()), true
+	default:
+		return 0, false
+	}
+}
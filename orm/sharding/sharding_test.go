@@ -0,0 +1,251 @@
+package sharding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHash_Sharding(t *testing.T) {
+	alg := &Hash{
+		Key:          "UserId",
+		DBPattern:    "order_db_%d",
+		DBCount:      4,
+		TablePattern: "order_tab_%02d",
+		TableCount:   32,
+	}
+	testCases := []struct {
+		name    string
+		alg     *Hash
+		op      Op
+		val     any
+		wantRes []Dst
+		wantErr error
+	}{
+		{
+			name:    "eq",
+			alg:     alg,
+			op:      OpEQ,
+			val:     int64(35),
+			wantRes: []Dst{{DB: "order_db_0", Table: "order_tab_03"}},
+		},
+		{
+			name:    "last db",
+			alg:     alg,
+			op:      OpEQ,
+			val:     uint8(31),
+			wantRes: []Dst{{DB: "order_db_3", Table: "order_tab_31"}},
+		},
+		{
+			name:    "negative",
+			alg:     alg,
+			op:      OpEQ,
+			val:     -1,
+			wantRes: []Dst{{DB: "order_db_3", Table: "order_tab_31"}},
+		},
+		{
+			name: "pointer",
+			alg:  alg,
+			op:   OpEQ,
+			val: func() *int {
+				val := 8
+				return &val
+			}(),
+			wantRes: []Dst{{DB: "order_db_1", Table: "order_tab_08"}},
+		},
+		{
+			name:    "string",
+			alg:     &Hash{Key: "Name", TablePattern: "user_%d", TableCount: 2},
+			op:      OpEQ,
+			val:     "Tom",
+			wantRes: []Dst{{Table: "user_1"}},
+		},
+		{
+			name:    "range",
+			alg:     &Hash{Key: "Id", TablePattern: "user_%d", TableCount: 2},
+			op:      OpGT,
+			val:     10,
+			wantRes: []Dst{{Table: "user_0"}, {Table: "user_1"}},
+		},
+		{
+			name:    "unsupported key",
+			alg:     alg,
+			op:      OpEQ,
+			val:     1.5,
+			wantErr: NewErrUnsupportedKey(1.5),
+		},
+		{
+			name:    "invalid config",
+			alg:     &Hash{Key: "Id", TablePattern: "user_%d"},
+			op:      OpEQ,
+			val:     1,
+			wantErr: ErrInvalidConfig,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.alg.Sharding(tc.op, tc.val)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+	assert.Len(t, alg.Broadcast(), 32)
+}
+
+func TestRange_Sharding(t *testing.T) {
+	alg := &Range{
+		Key: "Id",
+		Ranges: []RangeShard{
+			{Start: 0, End: 100, Dst: Dst{Table: "order_0"}},
+			{Start: 100, End: 200, Dst: Dst{Table: "order_1"}},
+			{Start: 200, End: 300, Dst: Dst{Table: "order_2"}},
+		},
+	}
+	testCases := []struct {
+		name    string
+		op      Op
+		val     any
+		wantRes []Dst
+		wantErr error
+	}{
+		{
+			name:    "eq",
+			op:      OpEQ,
+			val:     100,
+			wantRes: []Dst{{Table: "order_1"}},
+		},
+		{
+			name:    "eq out of range",
+			op:      OpEQ,
+			val:     300,
+			wantRes: []Dst{},
+		},
+		{
+			name:    "lt",
+			op:      OpLT,
+			val:     100,
+			wantRes: []Dst{{Table: "order_0"}},
+		},
+		{
+			name:    "lteq",
+			op:      OpLTEQ,
+			val:     100,
+			wantRes: []Dst{{Table: "order_0"}, {Table: "order_1"}},
+		},
+		{
+			name:    "gt",
+			op:      OpGT,
+			val:     199,
+			wantRes: []Dst{{Table: "order_2"}},
+		},
+		{
+			name:    "gteq",
+			op:      OpGTEQ,
+			val:     199,
+			wantRes: []Dst{{Table: "order_1"}, {Table: "order_2"}},
+		},
+		{
+			name:    "unsupported key",
+			op:      OpEQ,
+			val:     "abc",
+			wantErr: NewErrUnsupportedKey("abc"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := alg.Sharding(tc.op, tc.val)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestDate_Sharding(t *testing.T) {
+	alg := &Date{
+		Key:         "CreatedAt",
+		DB:          "order_db",
+		TablePrefix: "order_tab_",
+		Unit:        UnitMonth,
+		Start:       time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:         time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+	dst := func(tables ...string) []Dst {
+		res := make([]Dst, 0, len(tables))
+		for _, table := range tables {
+			res = append(res, Dst{DB: "order_db", Table: table})
+		}
+		return res
+	}
+	testCases := []struct {
+		name    string
+		alg     *Date
+		op      Op
+		val     any
+		wantRes []Dst
+		wantErr error
+	}{
+		{
+			name:    "eq",
+			alg:     alg,
+			op:      OpEQ,
+			val:     time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC),
+			wantRes: dst("order_tab_202602"),
+		},
+		{
+			name:    "lt",
+			alg:     alg,
+			op:      OpLT,
+			val:     time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC),
+			wantRes: dst("order_tab_202601", "order_tab_202602"),
+		},
+		{
+			name:    "gteq",
+			alg:     alg,
+			op:      OpGTEQ,
+			val:     time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC),
+			wantRes: dst("order_tab_202603", "order_tab_202604"),
+		},
+		{
+			name:    "out of range",
+			alg:     alg,
+			op:      OpGT,
+			val:     time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+			wantRes: nil,
+		},
+		{
+			name: "day",
+			alg: &Date{Key: "CreatedAt", TablePrefix: "log_", Unit: UnitDay,
+				Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)},
+			op:      OpEQ,
+			val:     time.Date(2026, 1, 2, 23, 0, 0, 0, time.UTC),
+			wantRes: []Dst{{Table: "log_20260102"}},
+		},
+		{
+			name:    "unsupported key",
+			alg:     alg,
+			op:      OpEQ,
+			val:     "2026-01-01",
+			wantErr: NewErrUnsupportedKey("2026-01-01"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.alg.Sharding(tc.op, tc.val)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+	assert.Equal(t, dst("order_tab_202601", "order_tab_202602", "order_tab_202603", "order_tab_202604"),
+		alg.Broadcast())
+}
@@ -0,0 +1,344 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"scaffolding-go/orm/internal/errs"
+	"scaffolding-go/orm/model"
+	"scaffolding-go/orm/sharding"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ShardingOrder struct {
+	Id     int64 `orm:"pk"`
+	UserId int64
+	Amount int64
+}
+
+// shardingDB 0、1 号表在 order_db_0，2、3 号表在 order_db_1
+func shardingDB(t *testing.T) (*DB, sqlmock.Sqlmock, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	r := model.NewRegistry()
+	_, err := r.Register(&ShardingOrder{}, model.WithSharding(&sharding.Hash{
+		Key:          "UserId",
+		DBPattern:    "order_db_%d",
+		DBCount:      2,
+		TablePattern: "order_tab_%d",
+		TableCount:   4,
+	}))
+	require.NoError(t, err)
+	mainDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db0, mock0, err := sqlmock.New()
+	require.NoError(t, err)
+	db1, mock1, err := sqlmock.New()
+	require.NoError(t, err)
+	// 同一个库上的分片是并发查询的，顺序不确定
+	mock0.MatchExpectationsInOrder(false)
+	mock1.MatchExpectationsInOrder(false)
+	db, err := OpenDB(mainDB, DBWithRegistry(r), DBWithShards(map[string]*sql.DB{
+		"order_db_0": db0,
+		"order_db_1": db1,
+	}))
+	require.NoError(t, err)
+	return db, mock, mock0, mock1
+}
+
+func TestShardingDsts(t *testing.T) {
+	db, _, _, _ := shardingDB(t)
+	m, err := db.r.Get(&ShardingOrder{})
+	require.NoError(t, err)
+	dst := func(idx ...int) []sharding.Dst {
+		res := make([]sharding.Dst, 0, len(idx))
+		all := m.Sharding.Broadcast()
+		for _, i := range idx {
+			res = append(res, all[i])
+		}
+		return res
+	}
+	testCases := []struct {
+		name    string
+		where   []Predicate
+		wantRes []sharding.Dst
+	}{
+		{
+			name:    "no where",
+			wantRes: dst(0, 1, 2, 3),
+		},
+		{
+			name:    "eq",
+			where:   []Predicate{C("UserId").Eq(5)},
+			wantRes: dst(1),
+		},
+		{
+			name:    "in",
+			where:   []Predicate{C("UserId").In(1, 2, 5)},
+			wantRes: dst(1, 2),
+		},
+		{
+			name:    "empty in",
+			where:   []Predicate{C("UserId").In()},
+			wantRes: nil,
+		},
+		{
+			name:    "or",
+			where:   []Predicate{C("UserId").Eq(1).Or(C("UserId").Eq(2))},
+			wantRes: dst(1, 2),
+		},
+		{
+			name:    "and other column",
+			where:   []Predicate{C("UserId").Eq(1), C("Id").Eq(3)},
+			wantRes: dst(1),
+		},
+		{
+			name:    "and conflict",
+			where:   []Predicate{C("UserId").Eq(1), C("UserId").Eq(2)},
+			wantRes: dst(),
+		},
+		{
+			name:    "or other column",
+			where:   []Predicate{C("UserId").Eq(1).Or(C("Id").Eq(3))},
+			wantRes: dst(1, 0, 2, 3),
+		},
+		{
+			name:    "range",
+			where:   []Predicate{C("UserId").GT(1)},
+			wantRes: dst(0, 1, 2, 3),
+		},
+		{
+			name:    "between",
+			where:   []Predicate{C("UserId").Between(1, 2)},
+			wantRes: dst(0, 1, 2, 3),
+		},
+		{
+			name:    "not",
+			where:   []Predicate{Not(C("UserId").Eq(1))},
+			wantRes: dst(0, 1, 2, 3),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := shardingDsts(m.Sharding, tc.where)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestSelector_Sharding(t *testing.T) {
+	db, _, mock0, mock1 := shardingDB(t)
+	ctx := context.Background()
+	cols := []string{"id", "user_id", "amount"}
+
+	// 只命中一个分片
+	mock0.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `order_tab_1` WHERE `user_id` = ?;")).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, 5, 100))
+	order, err := NewSelector[ShardingOrder](db).Where(C("UserId").Eq(5)).Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &ShardingOrder{Id: 1, UserId: 5, Amount: 100}, order)
+
+	// 排序和分页在内存里面合并
+	mock0.ExpectQuery(regexp.QuoteMeta(
+		"SELECT * FROM `order_tab_1` WHERE `user_id` IN (?,?) ORDER BY `amount` DESC LIMIT ?;")).
+		WithArgs(1, 2, 3).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, 1, 300).AddRow(2, 1, 100))
+	mock1.ExpectQuery(regexp.QuoteMeta(
+		"SELECT * FROM `order_tab_2` WHERE `user_id` IN (?,?) ORDER BY `amount` DESC LIMIT ?;")).
+		WithArgs(1, 2, 3).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(3, 2, 200).AddRow(4, 2, 50))
+	orders, err := NewSelector[ShardingOrder](db).Where(C("UserId").In(1, 2)).
+		OrderBy(Desc("Amount")).Limit(2).Offset(1).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*ShardingOrder{{Id: 3, UserId: 2, Amount: 200}, {Id: 2, UserId: 1, Amount: 100}}, orders)
+
+	// Get 每个分片最多查出 offset + 1 行
+	mock0.ExpectQuery(regexp.QuoteMeta(
+		"SELECT * FROM `order_tab_1` WHERE `user_id` IN (?,?) ORDER BY `amount` DESC LIMIT ?;")).
+		WithArgs(1, 2, 1).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, 1, 300))
+	mock1.ExpectQuery(regexp.QuoteMeta(
+		"SELECT * FROM `order_tab_2` WHERE `user_id` IN (?,?) ORDER BY `amount` DESC LIMIT ?;")).
+		WithArgs(1, 2, 1).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(3, 2, 500))
+	order, err = NewSelector[ShardingOrder](db).Where(C("UserId").In(1, 2)).
+		OrderBy(Desc("Amount")).Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &ShardingOrder{Id: 3, UserId: 2, Amount: 500}, order)
+
+	// 聚合函数合并为一行
+	for i, mock := range []sqlmock.Sqlmock{mock0, mock0, mock1, mock1} {
+		mock.ExpectQuery("SELECT COUNT\\(`id`\\) AS `id`,MAX\\(`amount`\\) AS `amount` FROM `order_tab_[0-3]`;").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}).AddRow(2, i*10))
+	}
+	order, err = NewSelector[ShardingOrder](db).Select(Count("Id").As("id"), Max("Amount").As("amount")).Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &ShardingOrder{Id: 8, Amount: 30}, order)
+
+	// 没有命中任何分片
+	_, err = NewSelector[ShardingOrder](db).Where(C("UserId").In()).Get(ctx)
+	assert.Equal(t, ErrNoRows, err)
+
+	_, err = NewSelector[ShardingOrder](db).GroupBy(C("UserId")).GetMulti(ctx)
+	assert.Equal(t, errs.NewErrShardingUnsupported("GROUP BY"), err)
+	_, err = NewSelector[ShardingOrder](db).Select(Avg("Amount").As("amount")).Get(ctx)
+	assert.Equal(t, errs.NewErrShardingUnsupported("AVG"), err)
+	for _, err = range NewSelector[ShardingOrder](db).Iter(ctx) {
+		assert.Equal(t, errs.NewErrShardingUnsupported("Iter"), err)
+	}
+
+	assert.NoError(t, mock0.ExpectationsWereMet())
+	assert.NoError(t, mock1.ExpectationsWereMet())
+}
+
+func TestSharding_Exec(t *testing.T) {
+	db, mock, mock0, mock1 := shardingDB(t)
+	ctx := context.Background()
+
+	// 按照分片键分组插入
+	mock0.ExpectExec(regexp.QuoteMeta(
+		"INSERT INTO `order_tab_1`(`id`,`user_id`,`amount`) VALUES (?,?,?),(?,?,?);")).
+		WithArgs(1, 1, 10, 3, 5, 30).
+		WillReturnResult(sqlmock.NewResult(3, 2))
+	mock1.ExpectExec(regexp.QuoteMeta("INSERT INTO `order_tab_2`(`id`,`user_id`,`amount`) VALUES (?,?,?);")).
+		WithArgs(2, 2, 20).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	res := NewInserter[ShardingOrder](db).Values(
+		&ShardingOrder{Id: 1, UserId: 1, Amount: 10},
+		&ShardingOrder{Id: 2, UserId: 2, Amount: 20},
+		&ShardingOrder{Id: 3, UserId: 5, Amount: 30}).Exec(ctx)
+	require.NoError(t, res.Err())
+	affected, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), affected)
+	_, err = res.LastInsertId()
+	assert.Equal(t, errs.ErrMultiResultLastInsertId, err)
+
+	// 没有 WHERE 的时候按照实体的分片键
+	mock1.ExpectExec(regexp.QuoteMeta("UPDATE `order_tab_2` SET `user_id`=?,`amount`=? WHERE `id` = ?;")).
+		WithArgs(2, 25, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	res = NewUpdater[ShardingOrder](db).Update(&ShardingOrder{Id: 2, UserId: 2, Amount: 25}).Exec(ctx)
+	require.NoError(t, res.Err())
+
+	mock0.ExpectExec(regexp.QuoteMeta("DELETE FROM `order_tab_1` WHERE `user_id` IN (?,?);")).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock1.ExpectExec(regexp.QuoteMeta("DELETE FROM `order_tab_2` WHERE `user_id` IN (?,?);")).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	affected, err = NewDeleter[ShardingOrder](db).Where(C("UserId").In(1, 2)).Exec(ctx).RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), affected)
+
+	// 事务不能访问其它的库
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	txCtx, tx, err := db.BeginTxV2(ctx, nil)
+	require.NoError(t, err)
+	err = NewDeleter[ShardingOrder](db).Where(C("UserId").Eq(1)).Exec(txCtx).Err()
+	require.NoError(t, tx.Rollback())
+	assert.Equal(t, errs.NewErrShardingUnsupported("跨库事务"), err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mock0.ExpectationsWereMet())
+	assert.NoError(t, mock1.ExpectationsWereMet())
+}
+
+func TestSharding_Tx(t *testing.T) {
+	type ShardingLog struct {
+		Id     int64 `orm:"pk"`
+		UserId int64
+	}
+	r := model.NewRegistry()
+	_, err := r.Register(&ShardingLog{}, model.WithSharding(&sharding.Hash{
+		Key:          "UserId",
+		TablePattern: "log_tab_%d",
+		TableCount:   2,
+	}))
+	require.NoError(t, err)
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithRegistry(r))
+	require.NoError(t, err)
+	cols := []string{"id", "user_id"}
+
+	// 事务里面共用一个连接，按照分片的顺序一个一个执行
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `log_tab_0`;")).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(2, 2))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `log_tab_1`;")).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `log_tab_0`;")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `log_tab_1`;")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	err = db.DoTx(context.Background(), func(ctx context.Context, tx *Tx) error {
+		logs, err := NewSelector[ShardingLog](tx).GetMulti(ctx)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, []*ShardingLog{{Id: 2, UserId: 2}, {Id: 1, UserId: 1}}, logs)
+		return NewDeleter[ShardingLog](db).Exec(ctx).Err()
+	}, nil)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSharding_AutoUpdateTime(t *testing.T) {
+	type ShardingItem struct {
+		Id        int64 `orm:"pk"`
+		UserId    int64
+		Amount    int64
+		UpdatedAt int64 `orm:"auto_update_time"`
+	}
+	r := model.NewRegistry()
+	_, err := r.Register(&ShardingItem{}, model.WithSharding(&sharding.Hash{
+		Key:          "UserId",
+		DBPattern:    "item_db_%d",
+		DBCount:      2,
+		TablePattern: "item_tab_%d",
+		TableCount:   4,
+	}))
+	require.NoError(t, err)
+	mainDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	db0, mock0, err := sqlmock.New()
+	require.NoError(t, err)
+	db1, mock1, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mainDB, DBWithRegistry(r), DBWithShards(map[string]*sql.DB{
+		"item_db_0": db0,
+		"item_db_1": db1,
+	}))
+	require.NoError(t, err)
+	// 每次取时间都不一样，所有分片必须用同一个
+	var calls atomic.Int64
+	db.now = func() time.Time {
+		return testNow.Add(time.Duration(calls.Add(1)) * time.Second)
+	}
+	want := testNow.Add(time.Second).UnixMilli()
+
+	mock0.ExpectExec(regexp.QuoteMeta(
+		"UPDATE `item_tab_1` SET `amount`=?,`updated_at`=? WHERE `user_id` IN (?,?);")).
+		WithArgs(10, want, 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock1.ExpectExec(regexp.QuoteMeta(
+		"UPDATE `item_tab_2` SET `amount`=?,`updated_at`=? WHERE `user_id` IN (?,?);")).
+		WithArgs(10, want, 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	item := &ShardingItem{Amount: 10}
+	err = NewUpdater[ShardingItem](db).Update(item).Set(C("Amount")).
+		Where(C("UserId").In(1, 2)).Exec(context.Background()).Err()
+	require.NoError(t, err)
+	assert.Equal(t, want, item.UpdatedAt)
+	assert.NoError(t, mock0.ExpectationsWereMet())
+	assert.NoError(t, mock1.ExpectationsWereMet())
+}
@@ -0,0 +1,98 @@
+package orm
+
+// Subquery 代表子查询
+// 可以用在 FROM、JOIN、IN、EXISTS 以及 SELECT 部分
+//
+//	sub := NewSelector[Order](db).Select(C("UserId")).AsSubquery("sub")
+//	NewSelector[User](db).Where(C("Id").InQuery(sub))
+type Subquery struct {
+	s QueryBuilder
+	// 子查询的 FROM 部分，用于解析子查询的列
+	entity  TableReference
+	columns []Selectable
+	alias   string
+}
+
+func (s *Selector[T]) AsSubquery(alias string) Subquery {
+	tbl := s.table
+	if tbl == nil {
+		tbl = TableOf(new(T))
+	}
+	return Subquery{
+		s:       s,
+		entity:  tbl,
+		columns: s.columns,
+		alias:   alias,
+	}
+}
+
+func (Subquery) expr()       {}
+func (Subquery) selectable() {}
+func (Subquery) table()      {}
+
+// C 引用子查询里面的列
+// 如果子查询指定了列，那么只能引用这些列（或者它们的别名）
+func (s Subquery) C(name string) Column {
+	return Column{
+		table: s,
+		name:  name,
+	}
+}
+
+func (s Subquery) Join(right TableReference) *JoinBuilder {
+	return &JoinBuilder{
+		left:  s,
+		right: right,
+		typ:   "JOIN",
+	}
+}
+
+func (s Subquery) LeftJoin(right TableReference) *JoinBuilder {
+	return &JoinBuilder{
+		left:  s,
+		right: right,
+		typ:   "LEFT JOIN",
+	}
+}
+
+func (s Subquery) RightJoin(right TableReference) *JoinBuilder {
+	return &JoinBuilder{
+		left:  s,
+		right: right,
+		typ:   "RIGHT JOIN",
+	}
+}
+
+// InQuery 代表 c IN (子查询)
+func (c Column) InQuery(sub Subquery) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opIn,
+		right: sub,
+	}
+}
+
+// NotInQuery 代表 c NOT IN (子查询)
+func (c Column) NotInQuery(sub Subquery) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opNotIn,
+		right: sub,
+	}
+}
+
+// Exists 代表 EXISTS (子查询)
+func Exists(sub Subquery) Predicate {
+	return Predicate{
+		op:    opExists,
+		right: sub,
+	}
+}
+
+// NotExists 代表 NOT EXISTS (子查询)
+func NotExists(sub Subquery) Predicate {
+	return Predicate{
+		op:    opNotExists,
+		right: sub,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"scaffolding-go/orm/internal/errs"
+	"strconv"
 )
 
 var (
@@ -16,6 +17,8 @@ type Session interface {
 	getCore() core
 	queryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 	execContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	// readContext 只读的查询，DB 可能会发给从库
+	readContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 }
 
 type Tx struct {
@@ -24,6 +27,10 @@ type Tx struct {
 
 	// 给事务扩散用
 	done bool
+	// 已经创建的 SAVEPOINT 数量，用于生成名字
+	savepoints int
+	// 提交成功之后执行的回调
+	onCommit []func()
 }
 
 func (t *Tx) getCore() core {
@@ -34,22 +41,37 @@ func (t *Tx) queryContext(ctx context.Context, query string, args ...any) (*sql.
 	return t.tx.QueryContext(ctx, query, args...)
 }
 
+func (t *Tx) readContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
 func (t *Tx) execContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
 	return t.tx.ExecContext(ctx, query, args...)
 }
 
 func (t *Tx) Commit() error {
-	//t.done = true
-	return t.tx.Commit()
+	t.done = true
+	if err := t.tx.Commit(); err != nil {
+		return err
+	}
+	for _, fn := range t.onCommit {
+		fn()
+	}
+	return nil
+}
+
+// OnCommit 注册事务提交成功之后执行的回调，回滚的时候不会执行
+func (t *Tx) OnCommit(fn func()) {
+	t.onCommit = append(t.onCommit, fn)
 }
 
 func (t *Tx) Rollback() error {
-	//t.done = true
+	t.done = true
 	return t.tx.Rollback()
 }
 
 func (t *Tx) RollbackIfNotCommit() error {
-	//t.done = true
+	t.done = true
 	err := t.tx.Rollback()
 	if errors.Is(err, sql.ErrTxDone) {
 		return nil
@@ -57,12 +79,68 @@ func (t *Tx) RollbackIfNotCommit() error {
 	return err
 }
 
+// Propagation 事务的传播行为，决定 DoTxWithPropagation 怎么处理 ctx 里面已有的事务
+type Propagation uint8
+
+const (
+	// PropagationRequired 有事务就加入，没有就开启一个新事务
+	PropagationRequired Propagation = iota
+	// PropagationRequiresNew 总是开启一个新事务，和已有的事务互不影响
+	PropagationRequiresNew
+	// PropagationNested 有事务就创建一个 SAVEPOINT，fn 失败的时候只回滚到 SAVEPOINT
+	// 没有事务就开启一个新事务
+	PropagationNested
+	// PropagationSupports 有事务就加入，没有就不使用事务，此时 fn 拿到的 tx 是 nil
+	PropagationSupports
+	// PropagationNever 不使用事务，有事务的时候返回 errs.ErrTxNotAllowed
+	PropagationNever
+)
+
+// DoTx 在事务里面执行 fn，fn 返回 error 或者 panic 的时候回滚
+// 传播行为是 PropagationRequired，参考 DoTxWithPropagation
 func (db *DB) DoTx(ctx context.Context,
+	fn func(ctx context.Context, tx *Tx) error, opts *sql.TxOptions) error {
+	return db.DoTxWithPropagation(ctx, PropagationRequired, fn, opts)
+}
+
+// DoTxWithPropagation 按照传播行为在事务里面执行 fn
+// fn 拿到的 ctx 带着当前的事务，用这个 ctx 调用 db 构造的 Selector 之类的也会在事务里面执行
+// 加入已有事务的时候，提交和回滚由开启事务的那一层负责，opts 不会生效
+func (db *DB) DoTxWithPropagation(ctx context.Context, propagation Propagation,
+	fn func(ctx context.Context, tx *Tx) error, opts *sql.TxOptions) error {
+	tx, ok := db.txFromContext(ctx)
+	switch propagation {
+	case PropagationRequired:
+		if ok {
+			return fn(ctx, tx)
+		}
+		return db.doNewTx(ctx, fn, opts)
+	case PropagationRequiresNew:
+		return db.doNewTx(ctx, fn, opts)
+	case PropagationNested:
+		if ok {
+			return tx.doSavepoint(ctx, fn)
+		}
+		return db.doNewTx(ctx, fn, opts)
+	case PropagationSupports:
+		return fn(ctx, tx)
+	case PropagationNever:
+		if ok {
+			return errs.ErrTxNotAllowed
+		}
+		return fn(ctx, nil)
+	default:
+		return errs.NewErrUnsupportedPropagation(propagation)
+	}
+}
+
+func (db *DB) doNewTx(ctx context.Context,
 	fn func(ctx context.Context, tx *Tx) error, opts *sql.TxOptions) (err error) {
 	tx, err := db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
+	ctx = context.WithValue(ctx, txKey{}, tx)
 	panicked := true
 	defer func() {
 		if panicked || err != nil {
@@ -76,3 +154,25 @@ func (db *DB) DoTx(ctx context.Context,
 	panicked = false
 	return err
 }
+
+// doSavepoint 在已有的事务里面创建 SAVEPOINT 执行 fn
+func (t *Tx) doSavepoint(ctx context.Context,
+	fn func(ctx context.Context, tx *Tx) error) (err error) {
+	t.savepoints++
+	name := "sp_" + strconv.Itoa(t.savepoints)
+	if _, err = t.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			_, er := t.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			err = errs.NewErrFailedToRollbackTx(err, er, panicked)
+		} else {
+			_, err = t.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+		}
+	}()
+	err = fn(ctx, t)
+	panicked = false
+	return err
+}
@@ -0,0 +1,188 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"scaffolding-go/orm/internal/errs"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_DoTxWithPropagation(t *testing.T) {
+	insertSQL := regexp.QuoteMeta("INSERT INTO `test_model`(`id`,`first_name`,`age`,`last_name`) VALUES (?,?,?,?);")
+	insert := func(ctx context.Context, db *DB, id int64) error {
+		// 用 db 也会在 ctx 的事务里面执行
+		return NewInserter[TestModel](db).Values(&TestModel{Id: id}).Exec(ctx).Err()
+	}
+	testCases := []struct {
+		name    string
+		mock    func(mock sqlmock.Sqlmock)
+		fn      func(ctx context.Context, db *DB) error
+		wantErr error
+	}{
+		{
+			name: "required",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(insertSQL).WithArgs(1, "", 0, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(insertSQL).WithArgs(2, "", 0, nil).WillReturnResult(sqlmock.NewResult(2, 1))
+				mock.ExpectCommit()
+			},
+			fn: func(ctx context.Context, db *DB) error {
+				return db.DoTx(ctx, func(ctx context.Context, outer *Tx) error {
+					if err := insert(ctx, db, 1); err != nil {
+						return err
+					}
+					// 加入外层的事务
+					return db.DoTx(ctx, func(ctx context.Context, inner *Tx) error {
+						assert.Same(t, outer, inner)
+						return insert(ctx, db, 2)
+					}, nil)
+				}, nil)
+			},
+		},
+		{
+			name: "requires new",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectBegin()
+				mock.ExpectExec(insertSQL).WithArgs(2, "", 0, nil).WillReturnResult(sqlmock.NewResult(2, 1))
+				mock.ExpectCommit()
+				mock.ExpectRollback()
+			},
+			fn: func(ctx context.Context, db *DB) error {
+				return db.DoTx(ctx, func(ctx context.Context, outer *Tx) error {
+					err := db.DoTxWithPropagation(ctx, PropagationRequiresNew,
+						func(ctx context.Context, inner *Tx) error {
+							assert.NotSame(t, outer, inner)
+							return insert(ctx, db, 2)
+						}, nil)
+					if err != nil {
+						return err
+					}
+					// 外层回滚不影响内层
+					return errors.New("outer error")
+				}, nil)
+			},
+			wantErr: errs.NewErrFailedToRollbackTx(errors.New("outer error"), nil, false),
+		},
+		{
+			name: "nested",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(insertSQL).WithArgs(1, "", 0, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(insertSQL).WithArgs(2, "", 0, nil).WillReturnError(errors.New("db error"))
+				mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(insertSQL).WithArgs(3, "", 0, nil).WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectExec("RELEASE SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			fn: func(ctx context.Context, db *DB) error {
+				return db.DoTx(ctx, func(ctx context.Context, tx *Tx) error {
+					if err := insert(ctx, db, 1); err != nil {
+						return err
+					}
+					// 只回滚到 SAVEPOINT，外层的事务继续
+					err := db.DoTxWithPropagation(ctx, PropagationNested, func(ctx context.Context, tx *Tx) error {
+						return insert(ctx, db, 2)
+					}, nil)
+					assert.ErrorContains(t, err, "db error")
+					return db.DoTxWithPropagation(ctx, PropagationNested, func(ctx context.Context, tx *Tx) error {
+						return insert(ctx, db, 3)
+					}, nil)
+				}, nil)
+			},
+		},
+		{
+			name: "nested without tx",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			},
+			fn: func(ctx context.Context, db *DB) error {
+				return db.DoTxWithPropagation(ctx, PropagationNested, func(ctx context.Context, tx *Tx) error {
+					assert.NotNil(t, tx)
+					return nil
+				}, nil)
+			},
+		},
+		{
+			name: "supports",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(insertSQL).WithArgs(1, "", 0, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			fn: func(ctx context.Context, db *DB) error {
+				return db.DoTxWithPropagation(ctx, PropagationSupports, func(ctx context.Context, tx *Tx) error {
+					assert.Nil(t, tx)
+					return insert(ctx, db, 1)
+				}, nil)
+			},
+		},
+		{
+			name: "never",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			},
+			fn: func(ctx context.Context, db *DB) error {
+				return db.DoTx(ctx, func(ctx context.Context, tx *Tx) error {
+					return db.DoTxWithPropagation(ctx, PropagationNever, func(ctx context.Context, tx *Tx) error {
+						return nil
+					}, nil)
+				}, nil)
+			},
+			wantErr: errs.NewErrFailedToRollbackTx(errs.ErrTxNotAllowed, nil, false),
+		},
+		{
+			name: "unsupported propagation",
+			mock: func(mock sqlmock.Sqlmock) {},
+			fn: func(ctx context.Context, db *DB) error {
+				return db.DoTxWithPropagation(ctx, Propagation(100), func(ctx context.Context, tx *Tx) error {
+					return nil
+				}, nil)
+			},
+			wantErr: errs.NewErrUnsupportedPropagation(Propagation(100)),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			db, err := OpenDB(mockDB)
+			require.NoError(t, err)
+			tc.mock(mock)
+			err = tc.fn(context.Background(), db)
+			assert.Equal(t, tc.wantErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDB_BeginTxV2(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	ctx, tx, err := db.BeginTxV2(context.Background(), nil)
+	require.NoError(t, err)
+	_, same, err := db.BeginTxV2(ctx, nil)
+	require.NoError(t, err)
+	assert.Same(t, tx, same)
+
+	// 提交之后再开启就是新的事务
+	require.NoError(t, tx.Commit())
+	_, newTx, err := db.BeginTxV2(ctx, nil)
+	require.NoError(t, err)
+	assert.NotSame(t, tx, newTx)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,310 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"scaffolding-go/orm/internal/errs"
+	"scaffolding-go/orm/internal/valuer"
+	"time"
+)
+
+type Updater[T any] struct {
+	builder
+	sess    Session
+	val     *T
+	assigns []Assignable
+	where   []Predicate
+	// 没有调用 Set 的时候，是否跳过零值字段
+	skipZero bool
+}
+
+func NewUpdater[T any](sess Session) *Updater[T] {
+	c := sess.getCore()
+	return &Updater[T]{
+		builder: builder{
+			core:   c,
+			quoter: c.dialect.quoter(),
+		},
+		sess: sess,
+	}
+}
+
+// Update 指定要更新的实体
+// 如果没有调用 Set，那么默认更新实体除了主键、创建时间和软删除以外的所有字段
+// 如果没有调用 Where，那么默认按照实体的主键更新，模型没有主键的时候返回错误
+func (u *Updater[T]) Update(t *T) *Updater[T] {
+	u.val = t
+	return u
+}
+
+// Set 指定要更新的列
+// C("Name") 代表从实体里面读取 Name 的值
+// Assign("Age", 18) 代表直接把 Age 更新为 18
+// Assign("Age", C("Age").Add(1)) 代表 age = age + 1
+func (u *Updater[T]) Set(assigns ...Assignable) *Updater[T] {
+	u.assigns = assigns
+	return u
+}
+
+// SkipZeroValue 在没有调用 Set 的时候，只更新实体的非零值字段
+func (u *Updater[T]) SkipZeroValue() *Updater[T] {
+	u.skipZero = true
+	return u
+}
+
+func (u *Updater[T]) Where(ps ...Predicate) *Updater[T] {
+	u.where = ps
+	return u
+}
+
+func (u *Updater[T]) Build() (*Query, error) {
+	u.reset()
+	if u.model == nil {
+		var err error
+		u.model, err = u.r.Get(new(T))
+		if err != nil {
+			return nil, err
+		}
+	}
+	assigns, err := u.assignments()
+	if err != nil {
+		return nil, err
+	}
+	u.sb.WriteString("UPDATE ")
+	u.quote(u.model.TableName)
+	u.sb.WriteString(" SET ")
+	for idx, assign := range assigns {
+		if idx > 0 {
+			u.sb.WriteByte(',')
+		}
+		switch a := assign.(type) {
+		case Column:
+			if err = u.buildColumn(Column{name: a.name}); err != nil {
+				return nil, err
+			}
+			u.sb.WriteByte('=')
+			arg, err := u.creator(u.model, u.val).Field(a.name)
+			if err != nil {
+				return nil, err
+			}
+			u.writeArg(arg)
+		case Assignment:
+			if err = u.buildColumn(Column{name: a.col}); err != nil {
+				return nil, err
+			}
+			u.sb.WriteByte('=')
+			if err = u.buildExpression(valueOf(a.val)); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errs.NewErrUnsupportedAssignable(a)
+		}
+	}
+	where := u.where
+	if len(where) == 0 && u.val != nil {
+		// 没有指定条件的时候，按照实体的主键更新
+		if where, err = u.primaryKeyWhere(u.val); err != nil {
+			return nil, err
+		}
+	}
+	if u.locking() {
+		fd := u.model.Version
+		version, err := u.creator(u.model, u.val).Field(fd.GoName)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where[:len(where):len(where)], C(fd.GoName).Eq(version))
+	}
+	if len(where) > 0 {
+		u.sb.WriteString(" WHERE ")
+		p := where[0]
+		for i := 1; i < len(where); i++ {
+			p = p.And(where[i])
+		}
+		if err = u.buildExpression(p); err != nil {
+			return nil, err
+		}
+	}
+	u.sb.WriteByte(';')
+	return &Query{
+		SQL:  u.sb.String(),
+		Args: u.args,
+	}, nil
+}
+
+// assignments 计算最终要更新的列
+// auto_update_time 的字段总是更新为当前时间，除非用户在 Set 里面指定了
+// 乐观锁的版本号会加一，参考 lockVersion
+func (u *Updater[T]) assignments() ([]Assignable, error) {
+	res, err := u.columnAssignments()
+	if err != nil {
+		return nil, err
+	}
+	for _, av := range u.autoUpdateValues(u.now()) {
+		res = append(res, Assign(av.field, av.val))
+		if u.val != nil {
+			u.autoValues = append(u.autoValues, av)
+		}
+	}
+	if u.lockVersion() {
+		fd := u.model.Version
+		res = append(res, Assign(fd.GoName, C(fd.GoName).Add(1)))
+	}
+	return res, nil
+}
+
+// autoUpdateValues auto_update_time 的字段要更新的值，用户在 Set 里面指定了的不处理
+func (u *Updater[T]) autoUpdateValues(now time.Time) []autoValue {
+	var res []autoValue
+	for _, fd := range u.model.Fields {
+		if fd.AutoUpdateTime && !u.assigned(fd.GoName) {
+			res = append(res, autoValue{entity: u.val, field: fd.GoName, val: timeValue(fd, now)})
+		}
+	}
+	return res
+}
+
+// columnAssignments 用户指定的列，或者实体除了主键以外的列
+func (u *Updater[T]) columnAssignments() ([]Assignable, error) {
+	if len(u.assigns) > 0 {
+		for _, a := range u.assigns {
+			// 用 Column 更新，值是从实体里面读出来的
+			if _, ok := a.(Column); ok && u.val == nil {
+				return nil, errs.ErrUpdateWithoutEntity
+			}
+		}
+		return u.assigns[:len(u.assigns):len(u.assigns)], nil
+	}
+	if u.val == nil {
+		return nil, errs.ErrNoUpdatedColumns
+	}
+	val := u.creator(u.model, u.val)
+	res := make([]Assignable, 0, len(u.model.Fields))
+	for _, fd := range u.model.Fields {
+		// 创建时间不应该被更新，更新时间和版本号在外面处理
+		// 软删除的列只能通过 Delete 修改，不然会把已经删除的数据恢复或者误删
+		if fd.PrimaryKey || fd.AutoCreateTime || fd.AutoUpdateTime || fd.Version || fd.SoftDelete {
+			continue
+		}
+		if u.skipZero {
+			arg, err := val.Field(fd.GoName)
+			if err != nil {
+				return nil, err
+			}
+			if isZero(arg) {
+				continue
+			}
+		}
+		res = append(res, C(fd.GoName))
+	}
+	if len(res) == 0 {
+		return nil, errs.ErrNoUpdatedColumns
+	}
+	return res, nil
+}
+
+// assigned 用户是否在 Set 里面指定了这个字段
+func (u *Updater[T]) assigned(name string) bool {
+	for _, a := range u.assigns {
+		switch assign := a.(type) {
+		case Column:
+			if assign.name == name {
+				return true
+			}
+		case Assignment:
+			if assign.col == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// locking 模型有版本号字段，并且传入了实体的时候，使用乐观锁
+// 会在 WHERE 里面加上实体当前的版本号
+func (u *Updater[T]) locking() bool {
+	return u.model.Version != nil && u.val != nil
+}
+
+// lockVersion 是否要把版本号加一，用户自己 Set 了版本号的话就不处理
+// 没有传入实体的更新也要加一，这样拿着旧版本号的实体才会更新失败
+func (u *Updater[T]) lockVersion() bool {
+	return u.model.Version != nil && !u.assigned(u.model.Version.GoName)
+}
+
+func isZero(val any) bool {
+	if val == nil {
+		return true
+	}
+	return reflect.ValueOf(val).IsZero()
+}
+
+func (u *Updater[T]) Exec(ctx context.Context) Result {
+	var err error
+	u.model, err = u.r.Get(new(T))
+	if err != nil {
+		return Result{
+			err: err,
+		}
+	}
+	if h, ok := any(u.val).(BeforeUpdate); u.val != nil && ok {
+		if err = h.BeforeUpdate(ctx); err != nil {
+			return Result{
+				err: err,
+			}
+		}
+	}
+	var res sql.Result
+	if u.model.Sharding != nil {
+		res, err = u.shardingExec(ctx)
+	} else {
+		res, err = u.exec(ctx)
+	}
+	if err == nil && u.locking() {
+		err = u.checkVersion(res)
+	}
+	// 乐观锁冲突的时候没有更新到数据，不需要写回
+	if err == nil {
+		err = u.writeAutoValues()
+	}
+	return Result{
+		err: err,
+		res: res,
+	}
+}
+
+func (u *Updater[T]) exec(ctx context.Context) (sql.Result, error) {
+	res := exec(ctx, u.sess, u.core, &QueryContext{
+		Type:      "UPDATE",
+		Builder:   u,
+		Model:     u.model,
+		FullTable: len(u.where) == 0 && u.val == nil,
+	})
+	var sqlRes sql.Result
+	if res.Result != nil {
+		sqlRes = res.Result.(sql.Result)
+	}
+	return sqlRes, res.Err
+}
+
+// checkVersion 没有更新到数据说明版本号对不上，更新成功之后把实体的版本号加一
+func (u *Updater[T]) checkVersion(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrOptimisticLockConflict
+	}
+	if !u.lockVersion() {
+		return nil
+	}
+	fdVal, _ := valuer.FieldByName(reflect.ValueOf(u.val).Elem(), u.model.Version.GoName, true)
+	if fdVal.CanInt() {
+		fdVal.SetInt(fdVal.Int() + 1)
+	} else {
+		fdVal.SetUint(fdVal.Uint() + 1)
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"scaffolding-go/orm/internal/errs"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdater_Build(t *testing.T) {
+	db := memoryDB(t)
+	timeDB := memoryDB(t)
+	timeDB.now = func() time.Time {
+		return testNow
+	}
+	type PKModel struct {
+		Id   int64 `orm:"pk,auto_increment"`
+		Name string
+		Age  int8
+	}
+	testCases := []struct {
+		name      string
+		u         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name:    "no columns",
+			u:       NewUpdater[TestModel](db),
+			wantErr: errs.ErrNoUpdatedColumns,
+		},
+		{
+			name: "entity without primary key",
+			u: NewUpdater[TestModel](db).Update(&TestModel{
+				Id:        12,
+				FirstName: "Tom",
+				Age:       18,
+				LastName:  &sql.NullString{String: "Jerry", Valid: true},
+			}),
+			wantErr: errs.ErrNoPrimaryKey,
+		},
+		{
+			name: "skip zero value",
+			u: NewUpdater[TestModel](db).Update(&TestModel{
+				FirstName: "Tom",
+				Age:       18,
+			}).SkipZeroValue().Where(C("Id").Eq(12)),
+			wantQuery: &Query{
+				SQL:  "UPDATE `test_model` SET `first_name`=?,`age`=? WHERE `id` = ?;",
+				Args: []any{"Tom", int8(18), 12},
+			},
+		},
+		{
+			name:    "all zero value",
+			u:       NewUpdater[TestModel](db).Update(&TestModel{}).SkipZeroValue(),
+			wantErr: errs.ErrNoUpdatedColumns,
+		},
+		{
+			name: "set columns",
+			u: NewUpdater[TestModel](db).Update(&TestModel{
+				Id:        12,
+				FirstName: "Tom",
+				Age:       18,
+			}).Set(C("FirstName"), Assign("Age", 19)).Where(C("Id").Eq(12)),
+			wantQuery: &Query{
+				SQL:  "UPDATE `test_model` SET `first_name`=?,`age`=? WHERE `id` = ?;",
+				Args: []any{"Tom", 19, 12},
+			},
+		},
+		{
+			name: "assign expression",
+			u: NewUpdater[TestModel](db).
+				Set(Assign("Age", C("Age").Add(1)), Assign("Id", C("Id").Multi(2).Add(1))).
+				Where(C("Id").Eq(12)),
+			wantQuery: &Query{
+				SQL:  "UPDATE `test_model` SET `age`=`age` + ?,`id`=(`id` * ?) + ? WHERE `id` = ?;",
+				Args: []any{1, 2, 1, 12},
+			},
+		},
+		{
+			name: "assign raw",
+			u: NewUpdater[TestModel](db).
+				Set(Assign("Age", Raw("`age`+?", 1))).
+				Where(C("Id").Eq(12).And(C("FirstName").Eq("Tom"))),
+			wantQuery: &Query{
+				SQL:  "UPDATE `test_model` SET `age`=(`age`+?) WHERE (`id` = ?) AND (`first_name` = ?);",
+				Args: []any{1, 12, "Tom"},
+			},
+		},
+		{
+			name: "assign func",
+			u: NewUpdater[TestModel](db).
+				Set(Assign("FirstName", Upper(C("FirstName"))), Assign("Age", C("Age").Sub(1).Div(2))).
+				Where(Lower(C("LastName")).Eq("jerry")),
+			wantQuery: &Query{
+				SQL:  "UPDATE `test_model` SET `first_name`=UPPER(`first_name`),`age`=(`age` - ?) / ? WHERE LOWER(`last_name`) = ?;",
+				Args: []any{1, 2, "jerry"},
+			},
+		},
+		{
+			name: "primary key",
+			u:    NewUpdater[PKModel](db).Update(&PKModel{Id: 12, Name: "Tom", Age: 18}),
+			wantQuery: &Query{
+				SQL:  "UPDATE `p_k_model` SET `name`=?,`age`=? WHERE `id` = ?;",
+				Args: []any{"Tom", int8(18), int64(12)},
+			},
+		},
+		{
+			name: "primary key set columns",
+			u: NewUpdater[PKModel](db).Update(&PKModel{Id: 12, Name: "Tom"}).
+				Set(C("Name")),
+			wantQuery: &Query{
+				SQL:  "UPDATE `p_k_model` SET `name`=? WHERE `id` = ?;",
+				Args: []any{"Tom", int64(12)},
+			},
+		},
+		{
+			name: "where over primary key",
+			u: NewUpdater[PKModel](db).Update(&PKModel{Id: 12, Name: "Tom"}).
+				SkipZeroValue().Where(C("Age").Eq(18)),
+			wantQuery: &Query{
+				SQL:  "UPDATE `p_k_model` SET `name`=? WHERE `age` = ?;",
+				Args: []any{"Tom", 18},
+			},
+		},
+		{
+			name:    "column without entity",
+			u:       NewUpdater[TestModel](db).Set(C("FirstName")),
+			wantErr: errs.ErrUpdateWithoutEntity,
+		},
+		{
+			name:    "invalid column",
+			u:       NewUpdater[TestModel](db).Set(Assign("Invalid", 1)),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+		{
+			name: "sqlite",
+			u: NewUpdater[TestModel](memoryDB(t, DBWithDialect(DialectSQLite))).
+				Set(Assign("Age", 18)).Where(C("Id").Eq(12)),
+			wantQuery: &Query{
+				SQL:  "UPDATE `test_model` SET `age`=? WHERE `id` = ?;",
+				Args: []any{18, 12},
+			},
+		},
+		{
+			name: "auto time all columns",
+			u: NewUpdater[TimeModel](timeDB).Update(&TimeModel{
+				Id: 1, Name: "Tom", CreatedAt: time.UnixMilli(100), UpdatedAt: 200}),
+			wantQuery: &Query{
+				SQL:  "UPDATE `time_model` SET `name`=?,`updated_at`=? WHERE `id` = ?;",
+				Args: []any{"Tom", testNow.UnixMilli(), int64(1)},
+			},
+		},
+		{
+			name: "auto time set",
+			u:    NewUpdater[TimeModel](timeDB).Set(Assign("Name", "Tom")).Where(C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "UPDATE `time_model` SET `name`=?,`updated_at`=? WHERE `id` = ?;",
+				Args: []any{"Tom", testNow.UnixMilli(), 1},
+			},
+		},
+		{
+			name: "auto time set by user",
+			u: NewUpdater[TimeModel](timeDB).Set(Assign("Name", "Tom"), Assign("UpdatedAt", 300)).
+				Where(C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "UPDATE `time_model` SET `name`=?,`updated_at`=? WHERE `id` = ?;",
+				Args: []any{"Tom", 300, 1},
+			},
+		},
+		{
+			name: "version",
+			u:    NewUpdater[VersionModel](db).Update(&VersionModel{Id: 1, Name: "Tom", Version: 3}),
+			wantQuery: &Query{
+				SQL: "UPDATE `version_model` SET `name`=?,`version`=`version` + ? " +
+					"WHERE (`id` = ?) AND (`version` = ?);",
+				Args: []any{"Tom", 1, int64(1), int64(3)},
+			},
+		},
+		{
+			name: "version with where",
+			u: NewUpdater[VersionModel](db).Update(&VersionModel{Id: 1, Name: "Tom", Version: 3}).
+				Set(C("Name")).Where(C("Name").Eq("Jerry")),
+			wantQuery: &Query{
+				SQL: "UPDATE `version_model` SET `name`=?,`version`=`version` + ? " +
+					"WHERE (`name` = ?) AND (`version` = ?);",
+				Args: []any{"Tom", 1, "Jerry", int64(3)},
+			},
+		},
+		{
+			name: "version without entity",
+			u:    NewUpdater[VersionModel](db).Set(Assign("Name", "Tom")).Where(C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "UPDATE `version_model` SET `name`=?,`version`=`version` + ? WHERE `id` = ?;",
+				Args: []any{"Tom", 1, 1},
+			},
+		},
+		{
+			name: "version set by user",
+			u: NewUpdater[VersionModel](db).Set(Assign("Name", "Tom"), Assign("Version", 5)).
+				Where(C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "UPDATE `version_model` SET `name`=?,`version`=? WHERE `id` = ?;",
+				Args: []any{"Tom", 5, 1},
+			},
+		},
+		{
+			name: "soft delete column excluded",
+			u:    NewUpdater[SoftDeleteModel](db).Update(&SoftDeleteModel{Id: 1, Name: "Tom"}),
+			wantQuery: &Query{
+				SQL:  "UPDATE `soft_delete_model` SET `name`=? WHERE `id` = ?;",
+				Args: []any{"Tom", int64(1)},
+			},
+		},
+		{
+			name: "soft delete set by user",
+			u: NewUpdater[SoftDeleteModel](db).Set(Assign("DeletedAt", nil)).
+				Where(C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "UPDATE `soft_delete_model` SET `deleted_at`=? WHERE `id` = ?;",
+				Args: []any{nil, 1},
+			},
+		},
+		{
+			name:    "auto time only",
+			u:       NewUpdater[TimeModel](timeDB).Update(&TimeModel{Id: 1}).SkipZeroValue(),
+			wantErr: errs.ErrNoUpdatedColumns,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.u.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+type VersionModel struct {
+	Id      int64 `orm:"pk"`
+	Name    string
+	Version int64 `orm:"version"`
+}
+
+func TestUpdater_Version(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	ctx := context.Background()
+	query := regexp.QuoteMeta("UPDATE `version_model` SET `name`=?,`version`=`version` + ? " +
+		"WHERE (`id` = ?) AND (`version` = ?);")
+
+	vm := &VersionModel{Id: 1, Name: "Tom", Version: 3}
+	mock.ExpectExec(query).WithArgs("Tom", 1, 1, 3).WillReturnResult(driver.RowsAffected(1))
+	require.NoError(t, NewUpdater[VersionModel](db).Update(vm).Exec(ctx).Err())
+	// 更新成功之后版本号加一
+	assert.Equal(t, int64(4), vm.Version)
+
+	// 别人已经修改了
+	mock.ExpectExec(query).WithArgs("Jerry", 1, 1, 4).WillReturnResult(driver.RowsAffected(0))
+	vm.Name = "Jerry"
+	err = NewUpdater[VersionModel](db).Update(vm).Exec(ctx).Err()
+	assert.Equal(t, ErrOptimisticLockConflict, err)
+	assert.Equal(t, int64(4), vm.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdater_Exec(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	var typ string
+	db, err := OpenDB(mockDB, DBWithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, qc *QueryContext) *QueryResult {
+			typ = qc.Type
+			return next(ctx, qc)
+		}
+	}))
+	require.NoError(t, err)
+	testCases := []struct {
+		name     string
+		u        *Updater[TestModel]
+		wantErr  error
+		affected int64
+	}{
+		{
+			name: "db error",
+			u: func() *Updater[TestModel] {
+				mock.ExpectExec("UPDATE .*").
+					WillReturnError(errors.New("db error"))
+				return NewUpdater[TestModel](db).Set(Assign("Age", 18))
+			}(),
+			wantErr: errors.New("db error"),
+		},
+		{
+			name:    "query error",
+			u:       NewUpdater[TestModel](db).Set(Assign("Invalid", 18)),
+			wantErr: errs.NewErrUnknownField("Invalid"),
+		},
+		{
+			name: "exec",
+			u: func() *Updater[TestModel] {
+				mock.ExpectExec("UPDATE .*").
+					WillReturnResult(driver.RowsAffected(2))
+				return NewUpdater[TestModel](db).Set(Assign("Age", 18))
+			}(),
+			affected: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := tc.u.Exec(context.Background())
+			assert.Equal(t, "UPDATE", typ)
+			assert.Equal(t, tc.wantErr, res.Err())
+			affected, err := res.RowsAffected()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.affected, affected)
+		})
+	}
+
+	// 自动生成的更新时间执行成功之后写回实体
+	db.now = func() time.Time {
+		return testNow
+	}
+	mock.ExpectExec("UPDATE .*").WillReturnResult(driver.RowsAffected(1))
+	tm := &TimeModel{Id: 1, Name: "Tom", UpdatedAt: 100}
+	require.NoError(t, NewUpdater[TimeModel](db).Update(tm).Exec(context.Background()).Err())
+	assert.Equal(t, testNow.UnixMilli(), tm.UpdatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}